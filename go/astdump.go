@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	config "github.com/breml/logstash-config"
+	"github.com/breml/logstash-config/ast"
+)
+
+// astNode is one node of the AST dump. Kind is "config", "section",
+// "plugin", "branch", "if", "else if", "else", "attribute", "entry",
+// "string", "number", "array", "hash" or "codec". Name is the section
+// type, plugin name, attribute name or hash key; Value is the text of
+// scalars and conditions. Offset, Line and Column are the parser's
+// positions; nodes the parser leaves without one (some nested numbers and
+// lists) have Line 0.
+type astNode struct {
+	Kind     string    `json:"kind"`
+	Name     string    `json:"name,omitempty"`
+	Value    string    `json:"value,omitempty"`
+	Offset   int       `json:"offset"`
+	Line     int       `json:"line"`
+	Column   int       `json:"column"`
+	Children []astNode `json:"children,omitempty"`
+}
+
+// astDumpResult is the JSON response of dumpLogstashAST: the tree, or the
+// first syntax error when the source doesn't parse.
+type astDumpResult struct {
+	OK    bool        `json:"ok"`
+	AST   *astNode    `json:"ast,omitempty"`
+	Error *Diagnostic `json:"error,omitempty"`
+}
+
+// dumpAST parses source into a plain tree of its sections, plugins,
+// branches and attributes, for debugging how a config is parsed and where
+// diagnostics point.
+func dumpAST(source string) astDumpResult {
+	parsed, err := config.Parse("", []byte(source))
+	if err != nil {
+		result := astDumpResult{}
+		if diags := parseErrorDiagnostics(source, err, 0); len(diags) > 0 {
+			newLineIndex(source).locate(&diags[0])
+			result.Error = &diags[0]
+		}
+		return result
+	}
+	cfg, ok := parsed.(ast.Config)
+	if !ok {
+		return astDumpResult{}
+	}
+
+	d := astDumper{input: source, lines: newLineIndex(source)}
+	root := astNode{Kind: "config", Line: 1, Column: 1}
+	for _, sections := range [][]ast.PluginSection{cfg.Input, cfg.Filter, cfg.Output} {
+		for _, section := range sections {
+			n := d.node("section", pluginTypeString(section.PluginType), section.Pos())
+			n.Children = d.block(section.BranchOrPlugins)
+			root.Children = append(root.Children, n)
+		}
+	}
+	return astDumpResult{OK: true, AST: &root}
+}
+
+// astDumper builds astNodes. delta is added to offsets, for nested codec
+// blocks parsed on their own (see codecBlock); their lines and columns are
+// then taken from the source.
+type astDumper struct {
+	input string
+	lines lineIndex
+	delta int
+}
+
+func (d astDumper) node(kind, name string, pos ast.Pos) astNode {
+	n := astNode{Kind: kind, Name: name, Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+	if pos.Line != 0 && d.delta != 0 {
+		n.Offset += d.delta
+		n.Line, n.Column = d.lines.position(n.Offset)
+	}
+	return n
+}
+
+func (d astDumper) block(block []ast.BranchOrPlugin) []astNode {
+	var nodes []astNode
+	for _, bop := range block {
+		switch node := bop.(type) {
+		case ast.Plugin:
+			nodes = append(nodes, d.plugin(node))
+		case ast.Branch:
+			branch := d.node("branch", "", node.IfBlock.Start)
+			ifNode := d.node("if", "", node.IfBlock.Start)
+			ifNode.Value = node.IfBlock.Condition.String()
+			ifNode.Children = d.block(node.IfBlock.Block)
+			branch.Children = append(branch.Children, ifNode)
+			for _, elseIf := range node.ElseIfBlock {
+				n := d.node("else if", "", elseIf.Start)
+				n.Value = elseIf.Condition.String()
+				n.Children = d.block(elseIf.Block)
+				branch.Children = append(branch.Children, n)
+			}
+			if node.ElseBlock.Start.Line != 0 || len(node.ElseBlock.Block) > 0 {
+				n := d.node("else", "", node.ElseBlock.Start)
+				n.Children = d.block(node.ElseBlock.Block)
+				branch.Children = append(branch.Children, n)
+			}
+			nodes = append(nodes, branch)
+		}
+	}
+	return nodes
+}
+
+func (d astDumper) plugin(plugin ast.Plugin) astNode {
+	n := d.node("plugin", plugin.Name(), plugin.Pos())
+	for _, attr := range plugin.Attributes {
+		if attr == nil {
+			continue
+		}
+		a := d.node("attribute", attr.Name(), attr.Pos())
+		a.Children = []astNode{d.value(attr)}
+		n.Children = append(n.Children, a)
+	}
+	return n
+}
+
+// value dumps an attribute's value; scalars and lists take their position
+// from the attribute, which for top-level values is the attribute's own.
+func (d astDumper) value(v ast.Attribute) astNode {
+	switch v := v.(type) {
+	case ast.StringAttribute:
+		n := d.node("string", "", v.Pos())
+		n.Value = v.ValueString()
+		return n
+	case ast.NumberAttribute:
+		n := d.node("number", "", v.Pos())
+		n.Value = v.ValueString()
+		return n
+	case ast.ArrayAttribute:
+		n := d.node("array", "", v.Pos())
+		for _, el := range v.Attributes {
+			if el != nil {
+				n.Children = append(n.Children, d.value(el))
+			}
+		}
+		return n
+	case ast.HashAttribute:
+		n := d.node("hash", "", v.Pos())
+		for _, entry := range v.Entries {
+			e := d.node("entry", entry.Name(), entry.Key.Pos())
+			e.Children = []astNode{d.value(entry.Value)}
+			n.Children = append(n.Children, e)
+		}
+		return n
+	case ast.PluginAttribute:
+		n := d.node("codec", "", v.Pos())
+		name := extractCodecName(v.ValueString())
+		codec, delta, ok := codecBlock(v, name, d.input)
+		if !ok {
+			n.Value = v.ValueString()
+			return n
+		}
+		n.Children = []astNode{astDumper{input: d.input, lines: d.lines, delta: delta}.plugin(codec)}
+		return n
+	}
+	n := d.node("unknown", "", v.Pos())
+	n.Value = v.ValueString()
+	return n
+}
+
+// getASTDump is the WASM entry point for the AST dump. Args: source.
+// Returns an astDumpResult.
+func getASTDump(this js.Value, args []js.Value) interface{} {
+	result := astDumpResult{}
+	if len(args) >= 1 {
+		result = dumpAST(args[0].String())
+	}
+	b, _ := json.Marshal(result)
+	return string(b)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"regexp"
 	"sort"
+	"strings"
 	"syscall/js"
 
 	"github.com/breml/logstash-config/ast"
@@ -10,15 +12,23 @@ import (
 
 // completionContext describes where the cursor is in the Logstash config.
 type completionContext struct {
-	Kind        string         // "section", "plugin", "option", "codec", "none"
-	SectionType ast.PluginType // valid when Kind is "plugin" or "option"
-	PluginName  string         // valid when Kind is "option"
+	Kind        string         // "section", "plugin", "option", "codec", "value", "condition", "regex", "field", "grok-pattern", "hash-key", "none"
+	SectionType ast.PluginType // valid when Kind is "plugin", "option", "codec" or "hash-key"
+	PluginName  string         // valid when Kind is "option", "codec" or "hash-key"
+	HashOption  string         // Kind "hash-key", and structural: the option whose hash value holds the cursor
+	CodecName   string         // structural only: the codec whose nested block (codec => json { }) holds the cursor
+	AfterBranch bool           // Kind "plugin": the cursor follows an if/else if block, so else may come next
+	Breadcrumb  []string       // structural only: section, plugin and hash options enclosing the cursor
 }
 
 type completionOption struct {
-	Label  string `json:"label"`
-	Type   string `json:"type"`
-	Detail string `json:"detail,omitempty"`
+	Label      string `json:"label"`
+	Type       string `json:"type"`
+	Detail     string `json:"detail,omitempty"`
+	Required   bool   `json:"required,omitempty"`   // options only
+	Deprecated bool   `json:"deprecated,omitempty"` // options only
+	InsertText string `json:"insertText,omitempty"` // text to insert instead of Label
+	Score      int    `json:"score,omitempty"`      // match against the typed word; options come sorted by it
 }
 
 type completionResult struct {
@@ -34,12 +44,15 @@ const (
 	framePlugin                       // grok { ... }
 	frameConditional                  // if ... { ... }
 	frameHash                         // match => { ... }
+	frameCodec                        // codec => json { ... }
 )
 
 type frame struct {
 	kind        frameKind
 	sectionType ast.PluginType
-	pluginName  string // only for framePlugin
+	pluginName  string // only for framePlugin, and frameCodec's codec
+	optionName  string // only for frameHash opened by "option => {", and frameCodec's option
+	branch      bool   // only for frameConditional opened by if or else if
 }
 
 // detectContext determines the completion context at the given cursor position.
@@ -49,15 +62,30 @@ func detectContext(source string, pos int) completionContext {
 	}
 
 	// Pass A: Check if we're in a value position (after =>).
-	// Scan left from pos past the partial word, then past whitespace.
+	// Scan left from pos past the partial word, then past whitespace and
+	// comments.
+	if commentBefore(source, pos) >= 0 {
+		return completionContext{Kind: "none"} // cursor inside a comment
+	}
 	p := pos - 1
 	// Skip partial word
 	for p >= 0 && isIdentChar(source[p]) {
 		p--
 	}
-	// Skip whitespace
-	for p >= 0 && (source[p] == ' ' || source[p] == '\t' || source[p] == '\n' || source[p] == '\r') {
-		p--
+	for {
+		// Skip whitespace
+		for p >= 0 && (source[p] == ' ' || source[p] == '\t' || source[p] == '\n' || source[p] == '\r') {
+			p--
+		}
+		if p < 0 {
+			break
+		}
+		// Skip a comment ending the line, e.g. "codec => # note"
+		c := commentBefore(source, p+1)
+		if c < 0 {
+			break
+		}
+		p = c - 1
 	}
 	// Check for =>
 	if p >= 1 && source[p-1] == '=' && source[p] == '>' {
@@ -71,14 +99,17 @@ func detectContext(source string, pos int) completionContext {
 			ap--
 		}
 		attrName := source[ap+1 : nameEnd]
-		if attrName == "codec" {
-			return completionContext{Kind: "codec"}
+		// Record the enclosing plugin so codecs can be offered per plugin.
+		outer := detectStructuralContext(source, pos)
+		if isCodecOption(pluginTypeString(outer.SectionType), outer.PluginName, attrName) {
+			return completionContext{Kind: "codec", SectionType: outer.SectionType, PluginName: outer.PluginName}
 		}
-		return completionContext{Kind: "none"}
+		return completionContext{Kind: "value"}
 	}
 
 	// Pass B: Forward scan with brace-nesting stack.
 	var stack []frame
+	closedBranchAt := -1 // offset of the } that last closed an if/else if block
 	i := 0
 	for i < pos {
 		ch := source[i]
@@ -96,6 +127,7 @@ func detectContext(source string, pos int) completionContext {
 
 		// Skip double-quoted strings — detect cursor inside string
 		if ch == '"' {
+			start := i
 			i++
 			for i < pos && source[i] != '"' {
 				if source[i] == '\\' {
@@ -104,7 +136,10 @@ func detectContext(source string, pos int) completionContext {
 				i++
 			}
 			if i >= pos {
-				return completionContext{Kind: "none"}
+				if quotedKey(source, start, pos, stack) {
+					return hashKeyContext(stack)
+				}
+				return stringContext(source, pos)
 			}
 			i++ // skip closing quote
 			continue
@@ -112,6 +147,7 @@ func detectContext(source string, pos int) completionContext {
 
 		// Skip single-quoted strings — detect cursor inside string
 		if ch == '\'' {
+			start := i
 			i++
 			for i < pos && source[i] != '\'' {
 				if source[i] == '\\' {
@@ -120,7 +156,10 @@ func detectContext(source string, pos int) completionContext {
 				i++
 			}
 			if i >= pos {
-				return completionContext{Kind: "none"}
+				if quotedKey(source, start, pos, stack) {
+					return hashKeyContext(stack)
+				}
+				return stringContext(source, pos)
 			}
 			i++ // skip closing quote
 			continue
@@ -137,6 +176,9 @@ func detectContext(source string, pos int) completionContext {
 		// Closing brace
 		if ch == '}' {
 			if len(stack) > 0 {
+				if stack[len(stack)-1].branch {
+					closedBranchAt = i
+				}
 				stack = stack[:len(stack)-1]
 			}
 			i++
@@ -145,6 +187,7 @@ func detectContext(source string, pos int) completionContext {
 
 		// Check for => { (hash value)
 		if ch == '=' && i+1 < pos && source[i+1] == '>' {
+			option := optionBeforeArrow(source, i)
 			i += 2
 			// Skip whitespace after =>
 			for i < pos && (source[i] == ' ' || source[i] == '\t' || source[i] == '\n' || source[i] == '\r') {
@@ -152,7 +195,7 @@ func detectContext(source string, pos int) completionContext {
 			}
 			if i < pos && source[i] == '{' {
 				sectionType := currentSectionType(stack)
-				stack = append(stack, frame{kind: frameHash, sectionType: sectionType})
+				stack = append(stack, frame{kind: frameHash, sectionType: sectionType, optionName: option})
 				i++
 			}
 			continue
@@ -166,6 +209,30 @@ func detectContext(source string, pos int) completionContext {
 			}
 			ident := source[start:i]
 
+			// Conditions can contain braces and # inside regexes and
+			// strings; skip the whole condition to the body's opening brace.
+			if ident == "if" {
+				open, ok, operand := skipCondition(source, i, pos)
+				if !ok {
+					switch operand {
+					case 0:
+						if followsRegexOperator(source, pos) {
+							return completionContext{Kind: "regex", SectionType: currentSectionType(stack)}
+						}
+						return completionContext{Kind: "condition", SectionType: currentSectionType(stack)}
+					case '[':
+						if _, ok := fieldRefStart(source, pos); ok {
+							return completionContext{Kind: "field", SectionType: currentSectionType(stack)}
+						}
+					}
+					return completionContext{Kind: "none"} // inside a string, regex or comment
+				}
+				sectionType := currentSectionType(stack)
+				stack = append(stack, frame{kind: frameConditional, sectionType: sectionType, branch: true})
+				i = open + 1
+				continue
+			}
+
 			// Skip whitespace after identifier
 			j := i
 			for j < pos && (source[j] == ' ' || source[j] == '\t' || source[j] == '\n' || source[j] == '\r') {
@@ -181,8 +248,7 @@ func detectContext(source string, pos int) completionContext {
 					stack = append(stack, frame{kind: frameSection, sectionType: ast.Filter})
 				case "output":
 					stack = append(stack, frame{kind: frameSection, sectionType: ast.Output})
-				case "if", "else":
-					// Conditional: skip everything up to the opening brace
+				case "else":
 					sectionType := currentSectionType(stack)
 					stack = append(stack, frame{kind: frameConditional, sectionType: sectionType})
 				default:
@@ -211,21 +277,54 @@ func detectContext(source string, pos int) completionContext {
 		return completionContext{Kind: "section"}
 	}
 
+	// p is still the last character before the typed word (Pass A).
+	afterBranch := p >= 0 && p == closedBranchAt
 	top := stack[len(stack)-1]
 	switch top.kind {
 	case frameSection:
-		return completionContext{Kind: "plugin", SectionType: top.sectionType}
+		return completionContext{Kind: "plugin", SectionType: top.sectionType, AfterBranch: afterBranch}
 	case framePlugin:
 		return completionContext{Kind: "option", SectionType: top.sectionType, PluginName: top.pluginName}
 	case frameConditional:
-		return completionContext{Kind: "plugin", SectionType: top.sectionType}
+		return completionContext{Kind: "plugin", SectionType: top.sectionType, AfterBranch: afterBranch}
 	case frameHash:
-		return completionContext{Kind: "none"}
+		return hashKeyContext(stack)
 	}
 
 	return completionContext{Kind: "none"}
 }
 
+// quotedKey reports whether the string opened at quote, still open at pos,
+// is a key being typed in the hash on top of stack: it doesn't follow =>
+// and holds only a partial word.
+func quotedKey(source string, quote, pos int, stack []frame) bool {
+	if currentFrameKind(stack) != frameHash {
+		return false
+	}
+	for j := quote + 1; j < pos; j++ {
+		if !isIdentChar(source[j]) {
+			return false
+		}
+	}
+	p := quote
+	for p > 0 && isBlank(source[p-1]) {
+		p--
+	}
+	return p < 2 || source[p-2:p] != "=>"
+}
+
+// hashKeyContext is the context at a key position in the hash on top of
+// stack: "hash-key" when the hash is the value of a plugin option, e.g.
+// grok's match => { }, and "none" for other hashes.
+func hashKeyContext(stack []frame) completionContext {
+	top := stack[len(stack)-1]
+	if top.optionName == "" || len(stack) < 2 || stack[len(stack)-2].kind != framePlugin {
+		return completionContext{Kind: "none"}
+	}
+	plugin := stack[len(stack)-2]
+	return completionContext{Kind: "hash-key", SectionType: plugin.sectionType, PluginName: plugin.pluginName, HashOption: top.optionName}
+}
+
 func currentSectionType(stack []frame) ast.PluginType {
 	for i := len(stack) - 1; i >= 0; i-- {
 		if stack[i].sectionType != 0 {
@@ -242,6 +341,139 @@ func currentFrameKind(stack []frame) frameKind {
 	return stack[len(stack)-1].kind
 }
 
+// skipCondition scans an if/else-if condition starting at i and returns the
+// offset of the { opening the conditional body. Strings, regex literals and
+// [field][refs] are skipped so braces or # inside them don't confuse the
+// nesting scan. ok is false if end is reached first; operand then tells
+// what end falls inside: the opening quote, / or # of a string, regex or
+// comment, '[' for a field reference or list, or 0 for none of these.
+func skipCondition(source string, i, end int) (open int, ok bool, operand byte) {
+	brackets, squares := 0, 0
+	for i < end {
+		ch := source[i]
+		switch {
+		case ch == '"' || ch == '\'' || ch == '/':
+			i++
+			for i < end && source[i] != ch {
+				if source[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= end {
+				return end, false, ch
+			}
+		case ch == '#':
+			for i < end && source[i] != '\n' {
+				i++
+			}
+			if i >= end {
+				return end, false, ch
+			}
+			continue
+		case ch == '[' || ch == '(':
+			brackets++
+			if ch == '[' {
+				squares++
+			}
+		case ch == ']' || ch == ')':
+			if brackets > 0 {
+				brackets--
+			}
+			if ch == ']' && squares > 0 {
+				squares--
+			}
+		case ch == '{' && brackets == 0:
+			return i, true, 0
+		}
+		i++
+	}
+	if squares > 0 {
+		return end, false, '['
+	}
+	return end, false, 0
+}
+
+// stringContext is the completion context for a cursor inside a string:
+// grok pattern names right after %{ in a grok filter, a field reference
+// when the string (or a %{...} in it) starts with [, otherwise nothing.
+func stringContext(source string, pos int) completionContext {
+	if start, ok := fieldRefStart(source, pos); ok && start > 0 {
+		if ch := source[start-1]; ch == '"' || ch == '\'' || ch == '{' && start >= 2 && source[start-2] == '%' {
+			return completionContext{Kind: "field", SectionType: detectStructuralContext(source, pos).SectionType}
+		}
+	}
+
+	p := pos
+	for p > 0 && isIdentChar(source[p-1]) {
+		p--
+	}
+	if p >= 2 && source[p-2:p] == "%{" {
+		if outer := detectStructuralContext(source, pos); outer.SectionType == ast.Filter && outer.PluginName == "grok" {
+			return completionContext{Kind: "grok-pattern", SectionType: ast.Filter, PluginName: "grok"}
+		}
+	}
+	return completionContext{Kind: "none"}
+}
+
+// commentBefore returns the offset of the # starting the comment that runs
+// up to end, or -1 if end isn't in a comment. The scan starts at the top of
+// the source because quoted strings can span lines: a # inside one doesn't
+// count, nor does a quote inside a comment.
+func commentBefore(source string, end int) int {
+	start := -1
+	var quote byte
+	for i := 0; i < end; i++ {
+		ch := source[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case start >= 0:
+			if ch == '\n' {
+				start = -1
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			start = i
+		}
+	}
+	return start
+}
+
+// fieldRefStart returns the start of the field reference being typed at
+// pos, e.g. the first [ of "[a][b". ok is false if pos isn't in one.
+func fieldRefStart(source string, pos int) (start int, ok bool) {
+	i := pos
+	for i > 0 && isFieldChar(source[i-1]) {
+		i--
+	}
+	if i == 0 || source[i-1] != '[' {
+		return pos, false
+	}
+	start = i - 1
+	// Include preceding complete elements: [a][b
+	for start > 0 && source[start-1] == ']' {
+		j := start - 1
+		for j > 0 && isFieldChar(source[j-1]) {
+			j--
+		}
+		if j == 0 || source[j-1] != '[' {
+			break
+		}
+		start = j - 1
+	}
+	return start, true
+}
+
+func isFieldChar(ch byte) bool {
+	return isIdentChar(ch) || ch == '@' || ch == '.' || ch == '-'
+}
+
 func isIdentStart(ch byte) bool {
 	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
 }
@@ -250,9 +482,63 @@ func isIdentChar(ch byte) bool {
 	return isIdentStart(ch) || (ch >= '0' && ch <= '9')
 }
 
+// rankCompletions keeps the options matching the typed word, scored by
+// fuzzyScore and sorted best first. Options that score the same keep their
+// order (required first, deprecated last).
+func rankCompletions(options []completionOption, word string) []completionOption {
+	ranked := options[:0]
+	for _, o := range options {
+		if o.Score = fuzzyScore(word, o.Label); o.Score > 0 {
+			ranked = append(ranked, o)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked
+}
+
+// fuzzyScore rates how well a typed word matches a candidate, ignoring
+// case: 100 for a prefix, 80 for a substring, 30-70 for the word's letters
+// in order (higher when closer together), 10-15 for a typo of the
+// candidate's start (words of 4+ characters), 0 for no match.
+func fuzzyScore(word, candidate string) int {
+	word, candidate = strings.ToLower(word), strings.ToLower(candidate)
+	switch {
+	case strings.HasPrefix(candidate, word):
+		return 100
+	case strings.Contains(candidate, word):
+		return 80
+	}
+
+	// Subsequence: score by how compact the matched span is.
+	first, j := -1, 0
+	for i := 0; i < len(candidate) && j < len(word); i++ {
+		if candidate[i] == word[j] {
+			if first < 0 {
+				first = i
+			}
+			j++
+			if j == len(word) {
+				return 30 + 40*len(word)/(i-first+1)
+			}
+		}
+	}
+
+	// Typo: compare with the candidate's start of the same length.
+	if len(word) >= 4 {
+		prefix := candidate[:min(len(word), len(candidate))]
+		if d := editDistance(word, prefix); d <= 2 {
+			return 20 - 5*d
+		}
+	}
+	return 0
+}
+
 // buildCompletions generates completion options based on the detected context.
 func buildCompletions(ctx completionContext) []completionOption {
 	switch ctx.Kind {
+	case "regex":
+		return regexCompletions
+
 	case "section":
 		return []completionOption{
 			{Label: "input", Type: "keyword", Detail: "section"},
@@ -264,19 +550,31 @@ func buildCompletions(ctx completionContext) []completionOption {
 		mu.RLock()
 		plugins := knownPlugins[ctx.SectionType]
 		mu.RUnlock()
-		if plugins == nil {
-			return nil
+		// else and else if, when valid here, come before the plugins.
+		var opts []completionOption
+		if ctx.AfterBranch {
+			opts = append(opts, elseCompletions...)
 		}
+		keywords := len(opts)
 		typeName := pluginTypeString(ctx.SectionType)
-		opts := make([]completionOption, 0, len(plugins))
 		for name := range plugins {
 			opts = append(opts, completionOption{
 				Label:  name,
 				Type:   "type",
 				Detail: typeName + " plugin",
 			})
+			// Snippet variant with the required options pre-filled.
+			if required := requiredOptions(typeName, name); len(required) > 0 {
+				opts = append(opts, completionOption{
+					Label:      name,
+					Type:       "type",
+					Detail:     typeName + " plugin with required options",
+					InsertText: pluginSnippet(name, required),
+				})
+			}
 		}
-		sort.Slice(opts, func(i, j int) bool { return opts[i].Label < opts[j].Label })
+		named := opts[keywords:]
+		sort.SliceStable(named, func(i, j int) bool { return named[i].Label < named[j].Label })
 		return opts
 
 	case "option":
@@ -284,39 +582,199 @@ func buildCompletions(ctx completionContext) []completionOption {
 		if known == nil {
 			return nil
 		}
+		typeName := pluginTypeString(ctx.SectionType)
 		opts := make([]completionOption, 0, len(known))
 		for name := range known {
-			opts = append(opts, completionOption{
+			opt := completionOption{
 				Label:  name,
 				Type:   "property",
 				Detail: "option",
-			})
+			}
+			if isCommonOption(ctx.SectionType, name) {
+				opt.Detail = "common option"
+			}
+			if od := getOptionDocInfo(typeName, ctx.PluginName, name); od != nil {
+				opt.Required = od.Required
+				opt.Deprecated = od.Deprecated != ""
+			}
+			if opt.Deprecated {
+				opt.Detail += " (deprecated)"
+			}
+			opts = append(opts, opt)
 		}
-		sort.Slice(opts, func(i, j int) bool { return opts[i].Label < opts[j].Label })
+		// Alphabetical, with deprecated options last.
+		sort.Slice(opts, func(i, j int) bool {
+			if opts[i].Deprecated != opts[j].Deprecated {
+				return !opts[i].Deprecated
+			}
+			return opts[i].Label < opts[j].Label
+		})
 		return opts
 
 	case "codec":
-		mu.RLock()
-		codecs := knownCodecs
-		mu.RUnlock()
+		codecs := availableCodecs(ctx.SectionType, ctx.PluginName)
 		if codecs == nil {
 			return nil
 		}
 		opts := make([]completionOption, 0, len(codecs))
-		for name := range codecs {
+		for _, name := range codecs {
 			opts = append(opts, completionOption{
 				Label:  name,
 				Type:   "enum",
 				Detail: "codec",
 			})
 		}
-		sort.Slice(opts, func(i, j int) bool { return opts[i].Label < opts[j].Label })
+		return opts
+
+	case "condition":
+		return conditionCompletions
+
+	case "grok-pattern":
+		opts := make([]completionOption, 0, len(grokPatterns))
+		for _, name := range grokPatterns {
+			opts = append(opts, completionOption{Label: name, Type: "constant", Detail: "grok pattern"})
+		}
+		return opts
+
+	case "hash-key":
+		od := getOptionDocInfo(pluginTypeString(ctx.SectionType), ctx.PluginName, ctx.HashOption)
+		if od == nil {
+			return nil
+		}
+		// The editor selects the "..." placeholder for the value.
+		opts := make([]completionOption, 0, len(od.HashKeys))
+		for _, key := range od.HashKeys {
+			opts = append(opts, completionOption{
+				Label:      key,
+				Type:       "property",
+				Detail:     ctx.HashOption + " key",
+				InsertText: `"` + key + `" => "..."`,
+			})
+		}
 		return opts
 	}
 
 	return nil
 }
 
+// regexCompletions are offered for the operand of =~ and !~. Logstash
+// regexes take no trailing flags, so case-insensitivity is the inline (?i).
+// The editor selects the "..." placeholder.
+var regexCompletions = []completionOption{
+	{Label: "/^.../", Type: "snippet", Detail: "starts with", InsertText: "/^.../"},
+	{Label: "/...$/", Type: "snippet", Detail: "ends with", InsertText: "/...$/"},
+	{Label: "/^...$/", Type: "snippet", Detail: "matches the whole value", InsertText: "/^...$/"},
+	{Label: "/(?i).../", Type: "snippet", Detail: "case-insensitive", InsertText: "/(?i).../"},
+}
+
+// followsRegexOperator reports whether the cursor, past any partial word
+// and blanks, directly follows =~ or !~.
+func followsRegexOperator(source string, pos int) bool {
+	p := pos
+	for p > 0 && isIdentChar(source[p-1]) {
+		p--
+	}
+	for p > 0 && isBlank(source[p-1]) {
+		p--
+	}
+	return p >= 2 && source[p-1] == '~' && (source[p-2] == '=' || source[p-2] == '!')
+}
+
+// elseCompletions are offered, with plugin names, right after the } of an
+// if or else if block.
+var elseCompletions = []completionOption{
+	{Label: "else", Type: "keyword", Detail: "conditional"},
+	{Label: "else if", Type: "keyword", Detail: "conditional"},
+}
+
+// conditionCompletions are the operators offered inside an if/else if
+// condition. Conditions have no boolean literals: [flag] == true doesn't parse.
+var conditionCompletions = []completionOption{
+	{Label: "==", Type: "operator", Detail: "equals"},
+	{Label: "!=", Type: "operator", Detail: "not equals"},
+	{Label: "=~", Type: "operator", Detail: "matches regex"},
+	{Label: "!~", Type: "operator", Detail: "does not match regex"},
+	{Label: "in", Type: "keyword", Detail: "contained in"},
+	{Label: "not in", Type: "keyword", Detail: "not contained in"},
+	{Label: "and", Type: "keyword", Detail: "boolean operator"},
+	{Label: "or", Type: "keyword", Detail: "boolean operator"},
+	{Label: "nand", Type: "keyword", Detail: "boolean operator"},
+	{Label: "xor", Type: "keyword", Detail: "boolean operator"},
+}
+
+// Field names introduced by the config itself, found by scanning the source.
+var (
+	// add_field => { "name" => ... } and copy => { "src" => "dest" }
+	fieldHashRegex = regexp.MustCompile(`\b(add_field|rename|copy)\s*=>\s*\{((?:[^{}]|%\{[^{}]*\})*)\}`)
+	hashPairRegex  = regexp.MustCompile(`("[^"]*"|'[^']*'|[\w@.-]+)\s*=>\s*("[^"]*"|'[^']*'|[\w@.-]+)`)
+	// grok %{PATTERN:name} or %{PATTERN:name:type}, and (?<name>...)
+	grokCaptureRegex = regexp.MustCompile(`%\{\w+:([^:}]+)(?::\w+)?\}|\(\?<(\w+)>`)
+)
+
+// collectFieldNames heuristically finds the fields a config creates through
+// add_field, rename and copy (the new names) and grok captures, in bracket
+// form ([a][b]), sorted. Names built with %{...} are skipped, and so is the
+// one being typed at cursorPos, which isn't a field yet.
+func collectFieldNames(source string, cursorPos int) []string {
+	seen := map[string]bool{}
+	add := func(start, end int) {
+		if start < 0 || (start <= cursorPos && cursorPos <= end) {
+			return
+		}
+		name := unquote(source[start:end])
+		if name != "" && !strings.Contains(name, "%{") {
+			seen[fieldRef(name)] = true
+		}
+	}
+	for _, m := range fieldHashRegex.FindAllStringSubmatchIndex(source, -1) {
+		body := source[m[4]:m[5]]
+		for _, pair := range hashPairRegex.FindAllStringSubmatchIndex(body, -1) {
+			if source[m[2]:m[3]] == "add_field" {
+				add(m[4]+pair[2], m[4]+pair[3])
+			} else {
+				add(m[4]+pair[4], m[4]+pair[5]) // the new name
+			}
+		}
+	}
+	for _, m := range grokCaptureRegex.FindAllStringSubmatchIndex(source, -1) {
+		add(m[2], m[3])
+		add(m[4], m[5])
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldCompletions offers the fields found by collectFieldNames, quoted for
+// value positions.
+func fieldCompletions(source string, cursorPos int, quoted bool) []completionOption {
+	names := collectFieldNames(source, cursorPos)
+	opts := make([]completionOption, 0, len(names))
+	for _, name := range names {
+		if quoted {
+			name = `"` + name + `"`
+		}
+		opts = append(opts, completionOption{Label: name, Type: "variable", Detail: "field"})
+	}
+	return opts
+}
+
+// pluginSnippet renders a plugin block with empty values for the given
+// options, e.g. "file {\n  path => \n}".
+func pluginSnippet(name string, options []string) string {
+	var b strings.Builder
+	b.WriteString(name + " {\n")
+	for _, opt := range options {
+		b.WriteString("  " + opt + " => \n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
 // detectStructuralContext determines the structural nesting context at pos,
 // ignoring value positions, strings, and comments. Used by the sidebar
 // to always show relevant plugin/option info regardless of cursor detail.
@@ -385,14 +843,18 @@ func detectStructuralContext(source string, pos int) completionContext {
 		}
 
 		if ch == '=' && i+1 < len(source) && source[i+1] == '>' {
+			option := optionBeforeArrow(source, i)
 			i += 2
 			for i < len(source) && (source[i] == ' ' || source[i] == '\t' || source[i] == '\n' || source[i] == '\r') {
 				i++
 			}
 			if i < len(source) && source[i] == '{' {
 				sectionType := currentSectionType(stack)
-				stack = append(stack, frame{kind: frameHash, sectionType: sectionType})
+				stack = append(stack, frame{kind: frameHash, sectionType: sectionType, optionName: option})
 				i++
+			} else if f, open, ok := codecFrame(source, i, option, stack); ok {
+				stack = append(stack, f)
+				i = open + 1
 			}
 			continue
 		}
@@ -404,6 +866,17 @@ func detectStructuralContext(source string, pos int) completionContext {
 			}
 			ident := source[start:i]
 
+			if ident == "if" {
+				open, ok, _ := skipCondition(source, i, pos)
+				if !ok {
+					break // cursor inside the condition: report the enclosing block
+				}
+				sectionType := currentSectionType(stack)
+				stack = append(stack, frame{kind: frameConditional, sectionType: sectionType})
+				i = open + 1
+				continue
+			}
+
 			j := i
 			for j < len(source) && (source[j] == ' ' || source[j] == '\t' || source[j] == '\n' || source[j] == '\r') {
 				j++
@@ -417,7 +890,7 @@ func detectStructuralContext(source string, pos int) completionContext {
 					stack = append(stack, frame{kind: frameSection, sectionType: ast.Filter})
 				case "output":
 					stack = append(stack, frame{kind: frameSection, sectionType: ast.Output})
-				case "if", "else":
+				case "else":
 					sectionType := currentSectionType(stack)
 					stack = append(stack, frame{kind: frameConditional, sectionType: sectionType})
 				default:
@@ -438,6 +911,57 @@ func detectStructuralContext(source string, pos int) completionContext {
 		i++
 	}
 
+	ctx := stackContext(stack)
+	ctx.Breadcrumb = breadcrumb(stack)
+	return ctx
+}
+
+// codecFrame returns the frame of a nested codec block when a codec name
+// and { follow, at i, the => of a codec option of the plugin whose block is
+// open, along with the offset of the {.
+func codecFrame(source string, i int, option string, stack []frame) (frame, int, bool) {
+	if currentFrameKind(stack) != framePlugin || i >= len(source) || !isIdentStart(source[i]) {
+		return frame{}, 0, false
+	}
+	top := stack[len(stack)-1]
+	if !isCodecOption(pluginTypeString(top.sectionType), top.pluginName, option) {
+		return frame{}, 0, false
+	}
+	end := i
+	for end < len(source) && isIdentChar(source[end]) {
+		end++
+	}
+	open := end
+	for open < len(source) && isBlank(source[open]) {
+		open++
+	}
+	if open >= len(source) || source[open] != '{' {
+		return frame{}, 0, false
+	}
+	return frame{kind: frameCodec, sectionType: top.sectionType, pluginName: source[i:end], optionName: option}, open, true
+}
+
+// breadcrumb names the blocks on the stack, e.g. ["filter", "grok", "match"];
+// conditionals and hashes not opened by an option are left out.
+func breadcrumb(stack []frame) []string {
+	var names []string
+	for _, f := range stack {
+		switch {
+		case f.kind == frameSection:
+			names = append(names, pluginTypeString(f.sectionType))
+		case f.kind == framePlugin:
+			names = append(names, f.pluginName)
+		case f.kind == frameCodec:
+			names = append(names, f.optionName, f.pluginName)
+		case f.kind == frameHash && f.optionName != "":
+			names = append(names, f.optionName)
+		}
+	}
+	return names
+}
+
+// stackContext is the structural context of the innermost block on stack.
+func stackContext(stack []frame) completionContext {
 	if len(stack) == 0 {
 		return completionContext{Kind: "section"}
 	}
@@ -450,11 +974,22 @@ func detectStructuralContext(source string, pos int) completionContext {
 		return completionContext{Kind: "option", SectionType: top.sectionType, PluginName: top.pluginName}
 	case frameConditional:
 		return completionContext{Kind: "plugin", SectionType: top.sectionType}
+	case frameCodec:
+		return codecContext(stack, len(stack)-1, "")
 	case frameHash:
 		// For hash values, walk up the stack to find the enclosing plugin
+		// or codec; the hash right above it belongs to its option.
 		for si := len(stack) - 2; si >= 0; si-- {
+			if stack[si].kind == frameCodec {
+				return codecContext(stack, si, stack[si+1].optionName)
+			}
 			if stack[si].kind == framePlugin {
-				return completionContext{Kind: "option", SectionType: stack[si].sectionType, PluginName: stack[si].pluginName}
+				return completionContext{
+					Kind:        "option",
+					SectionType: stack[si].sectionType,
+					PluginName:  stack[si].pluginName,
+					HashOption:  stack[si+1].optionName,
+				}
 			}
 		}
 		return completionContext{Kind: "none"}
@@ -463,6 +998,51 @@ func detectStructuralContext(source string, pos int) completionContext {
 	return completionContext{Kind: "none"}
 }
 
+// codecContext is the structural context inside the codec block at
+// stack[ci]: the options of the enclosing plugin's codec.
+func codecContext(stack []frame, ci int, hashOption string) completionContext {
+	ctx := completionContext{
+		Kind:        "option",
+		SectionType: stack[ci].sectionType,
+		CodecName:   stack[ci].pluginName,
+		HashOption:  hashOption,
+	}
+	for pi := ci - 1; pi >= 0; pi-- {
+		if stack[pi].kind == framePlugin {
+			ctx.PluginName = stack[pi].pluginName
+			break
+		}
+	}
+	return ctx
+}
+
+// optionBeforeArrow returns the option name (unquoted) before the => at
+// arrow, or "" if there is none.
+func optionBeforeArrow(source string, arrow int) string {
+	end := arrow
+	for end > 0 && isBlank(source[end-1]) {
+		end--
+	}
+	if end == 0 {
+		return ""
+	}
+	if q := source[end-1]; q == '"' || q == '\'' {
+		start := strings.LastIndexByte(source[:end-1], q)
+		for start > 0 && source[start-1] == '\\' { // an escaped quote
+			start = strings.LastIndexByte(source[:start], q)
+		}
+		if start < 0 {
+			return ""
+		}
+		return source[start+1 : end-1]
+	}
+	start := end
+	for start > 0 && isIdentChar(source[start-1]) {
+		start--
+	}
+	return source[start:end]
+}
+
 // getCompletions is the WASM entry point for code completion.
 func getCompletions(this js.Value, args []js.Value) interface{} {
 	if len(args) < 2 {
@@ -470,9 +1050,13 @@ func getCompletions(this js.Value, args []js.Value) interface{} {
 		return string(b)
 	}
 
-	source := args[0].String()
-	cursorPos := args[1].Int()
+	b, _ := json.Marshal(completionsAt(args[0].String(), args[1].Int()))
+	return string(b)
+}
 
+// completionsAt returns the completions at cursorPos and the offset From
+// which a chosen one replaces the text up to the cursor.
+func completionsAt(source string, cursorPos int) completionResult {
 	// Compute 'from' by scanning left from cursorPos past identifier chars
 	from := cursorPos
 	for from > 0 && isIdentChar(source[from-1]) {
@@ -481,14 +1065,35 @@ func getCompletions(this js.Value, args []js.Value) interface{} {
 
 	ctx := detectContext(source, cursorPos)
 	options := buildCompletions(ctx)
+	switch ctx.Kind {
+	case "plugin", "option", "codec", "hash-key":
+		if word := source[from:cursorPos]; word != "" {
+			options = rankCompletions(options, word)
+		}
+	}
+	if ctx.Kind == "hash-key" && from > 0 && (source[from-1] == '"' || source[from-1] == '\'') {
+		from-- // a quoted key: the snippet brings its own quotes
+	}
+	switch ctx.Kind {
+	case "value":
+		options = append(options, fieldCompletions(source, cursorPos, true)...)
+	case "condition":
+		options = append(options, fieldCompletions(source, cursorPos, false)...)
+	case "field":
+		from, _ = fieldRefStart(source, cursorPos)
+		options = append(options, fieldCompletions(source, cursorPos, false)...)
+	default:
+		// e.g. inside an array value: keywords never follow [
+		if from > 0 && source[from-1] == '[' {
+			options = nil
+		}
+	}
 	if options == nil {
 		options = []completionOption{}
 	}
 
-	result := completionResult{
+	return completionResult{
 		From:    from,
 		Options: options,
 	}
-	b, _ := json.Marshal(result)
-	return string(b)
 }
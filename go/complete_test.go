@@ -0,0 +1,187 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/breml/logstash-config/ast"
+)
+
+func TestFieldCompletionsSkipTokenUnderCursor(t *testing.T) {
+	const src = `filter { grok { match => { "message" => "%{WORD:user}" } } mutate { rename => { "user" => "[re]" } } if [u] { } }`
+	labels := func(pos int) map[string]bool {
+		got := map[string]bool{}
+		for _, o := range completionsAt(src, pos).Options {
+			if o.Type == "variable" {
+				got[o.Label] = true
+			}
+		}
+		return got
+	}
+
+	// Typing the new name in rename: it isn't offered back.
+	inRename := strings.Index(src, "[re]") + len("[re")
+	if got := labels(inRename); got["[re]"] || !got["[user]"] {
+		t.Errorf("in rename value: got %v, want [user] without [re]", got)
+	}
+	// In the condition the finished rename target is a field.
+	inCondition := strings.Index(src, "[u]") + len("[u")
+	if got := labels(inCondition); !got["[re]"] || !got["[user]"] {
+		t.Errorf("in condition: got %v, want [re] and [user]", got)
+	}
+}
+
+// cursorAt splits a source marked with | at the cursor into the source and
+// the cursor offset.
+func cursorAt(t *testing.T, marked string) (string, int) {
+	t.Helper()
+	pos := strings.Index(marked, "|")
+	if pos < 0 {
+		t.Fatalf("no cursor in %q", marked)
+	}
+	return marked[:pos] + marked[pos+1:], pos
+}
+
+func TestDetectContextNestedConditionals(t *testing.T) {
+	tests := []struct {
+		src     string
+		section ast.PluginType
+	}{
+		{"filter { if [a] { | } }", ast.Filter},
+		{"filter { if [a] { if [b] { | } } }", ast.Filter},
+		{"filter { if [a] { if [b] { } else { | } } }", ast.Filter},
+		{"filter { if [a] { mutate { } } else if [b] { if [c] { | } } }", ast.Filter},
+		{"output { if [a] { if [b] == \"x\" { stdout { } | } } }", ast.Output},
+		{"input { if [a] { if [b] { | } } } filter { }", ast.Input},
+	}
+	for _, tt := range tests {
+		src, pos := cursorAt(t, tt.src)
+		ctx := detectContext(src, pos)
+		if ctx.Kind != "plugin" || ctx.SectionType != tt.section {
+			t.Errorf("%s: got kind %q section %v, want plugin in %v", tt.src, ctx.Kind, ctx.SectionType, tt.section)
+		}
+		if len(buildCompletions(ctx)) == 0 {
+			t.Errorf("%s: no plugin completions", tt.src)
+		}
+	}
+}
+
+func TestConditionCompletions(t *testing.T) {
+	for _, marked := range []string{
+		"filter { if [status] | { } }",
+		"filter { if [a] { } else if [status] | { } }",
+		"output { if [status] == 200 and [type] | { } }",
+	} {
+		src, pos := cursorAt(t, marked)
+		if ctx := detectContext(src, pos); ctx.Kind != "condition" {
+			t.Errorf("%s: kind %q, want condition", marked, ctx.Kind)
+			continue
+		}
+		got := map[string]bool{}
+		for _, o := range completionsAt(src, pos).Options {
+			got[o.Label] = true
+		}
+		for _, op := range []string{"==", "!=", "=~", "!~", "in", "not in", "and", "or", "nand", "xor"} {
+			if !got[op] {
+				t.Errorf("%s: %q not offered", marked, op)
+			}
+		}
+		if got["mutate"] {
+			t.Errorf("%s: plugin names offered in a condition", marked)
+		}
+	}
+}
+
+func TestDeprecatedOptionsSortLast(t *testing.T) {
+	useRegistry(t, `{
+		"plugins": {"output": ["sink"]},
+		"pluginOptions": {"output/sink": ["alpha", "beta", "gamma"]},
+		"pluginDocs": {"output/sink": {"options": {
+			"alpha": {"type": "string", "deprecated": "Use beta instead."},
+			"beta": {"type": "string"},
+			"gamma": {"type": "string"}
+		}}}
+	}`)
+	src, pos := cursorAt(t, "output { sink { | } }")
+	var labels, details []string
+	for _, o := range completionsAt(src, pos).Options {
+		labels = append(labels, o.Label)
+		details = append(details, o.Detail)
+	}
+	if want := []string{"beta", "gamma", "alpha"}; !slices.Equal(labels, want) {
+		t.Errorf("order %q, want %q", labels, want)
+	}
+	if want := []string{"option", "option", "option (deprecated)"}; !slices.Equal(details, want) {
+		t.Errorf("details %q, want %q", details, want)
+	}
+}
+
+func TestDetectContextSkipsComments(t *testing.T) {
+	tests := []struct {
+		src, kind string
+	}{
+		{"filter {\n  mutate {\n    # note\n    | \n  }\n}", "option"},
+		{"filter {\n  mutate {\n    # note => with an arrow\n    | \n  }\n}", "option"},
+		{"filter {\n  mutate {\n    add_tag =>\n    # note\n    |\n  }\n}", "value"},
+		{"output {\n  stdout {\n    codec => # json or rubydebug\n      |\n  }\n}", "codec"},
+	}
+	for _, tt := range tests {
+		src, pos := cursorAt(t, tt.src)
+		if ctx := detectContext(src, pos); ctx.Kind != tt.kind {
+			t.Errorf("%q: kind %q, want %q", tt.src, ctx.Kind, tt.kind)
+		}
+	}
+}
+
+func TestElseCompletionsAfterBranch(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"filter {\n  if [a] {\n  }\n  |\n}", true},
+		{"filter {\n  if [a] { mutate { } } else if [b] { }\n  |\n}", true},
+		{"filter {\n  if [a] { } else { }\n  |\n}", false}, // else ends the chain
+		{"filter {\n  mutate { }\n  |\n}", false},
+		{"filter {\n  |\n}", false},
+	}
+	for _, tt := range tests {
+		src, pos := cursorAt(t, tt.src)
+		got := map[string]bool{}
+		for _, o := range completionsAt(src, pos).Options {
+			got[o.Label] = true
+		}
+		if got["else"] != tt.want || got["else if"] != tt.want {
+			t.Errorf("%q: else %v, else if %v, want %v", tt.src, got["else"], got["else if"], tt.want)
+		}
+		if !got["mutate"] {
+			t.Errorf("%q: plugins not offered", tt.src)
+		}
+	}
+}
+
+func TestContextAfterMultiLineStrings(t *testing.T) {
+	tests := []struct {
+		src, kind, plugin string
+	}{
+		// A string spanning lines with braces in it.
+		{"filter {\n  mutate {\n    add_field => { \"msg\" => \"line one {\nline two }\n}\" }\n    |\n  }\n}", "option", "mutate"},
+		// An escaped quote doesn't end the string.
+		{"filter {\n  mutate {\n    replace => { \"msg\" => \"say \\\"} {\\\" now\" }\n    |\n  }\n}", "option", "mutate"},
+		// Single quotes, multi-line, with a double quote inside.
+		{"filter {\n  ruby {\n    code => 'event.set(\"a\", \"}\")\n      x = 1'\n    |\n  }\n}", "option", "ruby"},
+		// After the plugin closes, back in the section.
+		{"filter {\n  mutate { add_tag => [\"a\n}\"] }\n  |\n}", "plugin", ""},
+	}
+	for _, tt := range tests {
+		src, pos := cursorAt(t, tt.src)
+		for name, ctx := range map[string]completionContext{
+			"detectContext":           detectContext(src, pos),
+			"detectStructuralContext": detectStructuralContext(src, pos),
+		} {
+			if ctx.Kind != tt.kind || ctx.SectionType != ast.Filter || ctx.PluginName != tt.plugin {
+				t.Errorf("%s(%q) = %q %v %q, want %q filter %q", name, tt.src, ctx.Kind, ctx.SectionType, ctx.PluginName, tt.kind, tt.plugin)
+			}
+		}
+	}
+}
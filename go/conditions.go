@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/breml/logstash-config/ast"
+)
+
+// grokNumericCaptureRegex matches grok captures with an int/float suffix,
+// e.g. %{NUMBER:bytes:int}. Group 1 is the field, group 2 the type.
+var grokNumericCaptureRegex = regexp.MustCompile(`%\{\w+:([^:}]+):(int|float)\}`)
+
+// numericConversions maps plugin conversion type names to whether they
+// produce a number.
+var numericConversions = map[string]bool{
+	"integer": true, "integer_eu": true, "float": true, "float_eu": true, // mutate
+	"int": true, // dissect
+}
+
+// conditionWalker tracks fields that earlier plugins convert to numbers,
+// in pipeline order, while checking conditions.
+type conditionWalker struct {
+	input   string
+	numeric map[string]string // field ref ([a][b]) -> plugin that converts it
+	diags   []Diagnostic
+}
+
+// validateConditions checks regex operators in conditions. The grammar
+// already requires a string or regex on the right of =~/!~, so this looks at
+// the left operand: a number literal, or a field an earlier mutate/grok/
+// dissect converts to a number. Field inference is best-effort.
+func validateConditions(cfg ast.Config, input string, diags []Diagnostic) []Diagnostic {
+	w := &conditionWalker{input: input, numeric: map[string]string{}, diags: diags}
+	for _, sections := range [][]ast.PluginSection{cfg.Input, cfg.Filter, cfg.Output} {
+		for _, section := range sections {
+			w.walkBlock(section.BranchOrPlugins)
+		}
+	}
+	return w.diags
+}
+
+func (w *conditionWalker) walkBlock(block []ast.BranchOrPlugin) {
+	for _, bop := range block {
+		switch node := bop.(type) {
+		case ast.Plugin:
+			w.collectNumericFields(node)
+		case ast.Branch:
+			w.checkCondition(node.IfBlock.Condition)
+			w.walkBlock(node.IfBlock.Block)
+			for _, elseIf := range node.ElseIfBlock {
+				w.checkCondition(elseIf.Condition)
+				w.walkBlock(elseIf.Block)
+			}
+			w.walkBlock(node.ElseBlock.Block)
+		}
+	}
+}
+
+func (w *conditionWalker) checkCondition(cond ast.Condition) {
+	for _, expr := range cond.Expression {
+		switch e := expr.(type) {
+		case ast.ConditionExpression:
+			w.checkCondition(e.Condition)
+		case ast.NegativeConditionExpression:
+			w.checkCondition(e.Condition)
+		case ast.RegexpExpression:
+			w.checkRegexpExpression(e)
+		}
+	}
+}
+
+func (w *conditionWalker) checkRegexpExpression(e ast.RegexpExpression) {
+	op := e.RegexpOperator.String()
+	var msg string
+	switch lv := e.LValue.(type) {
+	case ast.NumberAttribute:
+		msg = fmt.Sprintf("%q matches strings, but the left operand is the number %s", op, lv.ValueString())
+	case ast.Selector:
+		field := lv.String()
+		plugin, ok := w.numeric[field]
+		if !ok {
+			return
+		}
+		msg = fmt.Sprintf("%q matches strings, but %s is converted to a number by %s earlier in the pipeline", op, field, plugin)
+	default:
+		return
+	}
+
+	// Highlight from the left operand through the operator.
+	from := clampFrom(e.Pos().Offset, w.input)
+	to := from
+	if i := strings.Index(w.input[from:], op); i >= 0 {
+		to = from + i + len(op)
+	}
+	w.diags = append(w.diags, Diagnostic{
+		From:     from,
+		To:       clampTo(to, w.input),
+		Severity: "warning",
+		Message:  msg,
+		Code:     codeRegexOnNumber,
+	})
+}
+
+// collectNumericFields records fields a plugin converts to numbers.
+func (w *conditionWalker) collectNumericFields(plugin ast.Plugin) {
+	name := plugin.Name()
+	switch name {
+	case "mutate", "dissect":
+		option := "convert"
+		if name == "dissect" {
+			option = "convert_datatype"
+		}
+		switch v := findAttribute(plugin, option).(type) {
+		case ast.HashAttribute:
+			for _, entry := range v.Entries {
+				if sa, ok := entry.Value.(ast.StringAttribute); ok && numericConversions[sa.Value()] {
+					w.numeric[fieldRef(hashKeyName(entry))] = name
+				}
+			}
+		case ast.ArrayAttribute:
+			// Legacy mutate form: [ "field", "type", ... ]
+			for i := 0; i+1 < len(v.Attributes); i += 2 {
+				field, ok1 := v.Attributes[i].(ast.StringAttribute)
+				kind, ok2 := v.Attributes[i+1].(ast.StringAttribute)
+				if ok1 && ok2 && numericConversions[kind.Value()] {
+					w.numeric[fieldRef(field.Value())] = name
+				}
+			}
+		}
+	case "grok":
+		if attr := findAttribute(plugin, "match"); attr != nil {
+			for _, sa := range stringAttributes(attr) {
+				for _, m := range grokNumericCaptureRegex.FindAllStringSubmatch(sa.Value(), -1) {
+					w.numeric[fieldRef(m[1])] = name
+				}
+			}
+		}
+	}
+}
+
+// notFieldNames are words that can't be meant as a bare field name in a
+// condition: operators, and boolean literals (which conditions don't have;
+// the parser's message is left alone for those).
+var notFieldNames = map[string]bool{
+	"and": true, "or": true, "xor": true, "nand": true, "in": true, "not": true,
+	"true": true, "false": true,
+}
+
+// bareConditionOperand returns the bare word at offset when it stands where
+// a condition operand was expected, as in `if foo == "bar"`. The parser
+// rejects those; the word was almost always meant as the field [foo].
+// Words followed by ( look like function calls and are not reported.
+func bareConditionOperand(input string, offset int) string {
+	if offset >= len(input) || !isIdentStart(input[offset]) && input[offset] != '@' {
+		return ""
+	}
+	end := offset
+	for end < len(input) && isFieldChar(input[end]) {
+		end++
+	}
+	name := input[offset:end]
+	if notFieldNames[name] {
+		return ""
+	}
+	if rest := strings.TrimLeft(input[end:], " \t"); strings.HasPrefix(rest, "(") {
+		return ""
+	}
+	if detectContext(input, offset).Kind != "condition" {
+		return ""
+	}
+	return name
+}
+
+// fieldRef normalizes a field name to bracket form: "a" -> "[a]".
+func fieldRef(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return name
+	}
+	return "[" + name + "]"
+}
@@ -2,22 +2,50 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"syscall/js"
 
+	config "github.com/breml/logstash-config"
 	"github.com/breml/logstash-config/ast"
 )
 
 // contextInfoResult is the structured response for the sidebar.
 type contextInfoResult struct {
 	Kind        string       `json:"kind"`                  // "top-level", "section", "plugin", "codec", "none"
-	SectionType string       `json:"sectionType,omitempty"` // "input", "filter", "output"
+	SectionType string       `json:"sectionType,omitempty"` // "input", "filter", "output"; "codec" inside a nested codec block
 	PluginName  string       `json:"pluginName,omitempty"`
 	PluginDoc   *pluginDoc   `json:"pluginDoc,omitempty"`
 	OptionName  string       `json:"optionName,omitempty"`
 	OptionDoc   *optionDoc   `json:"optionDoc,omitempty"`
 	Plugins     []pluginInfo `json:"plugins,omitempty"`
 	Options     []optionInfo `json:"options,omitempty"`
+	// OptionValueIssue describes a conflict between the value typed for the
+	// option on the cursor's line and the option's declared type.
+	OptionValueIssue string `json:"optionValueIssue,omitempty"`
+	// HashOption is set when the cursor is inside the hash value of this
+	// hash-typed option, e.g. the { } of grok's match.
+	HashOption string `json:"hashOption,omitempty"`
+	// Breadcrumb is the path to the cursor, e.g. ["filter", "grok", "match"]:
+	// section, plugin, then the option at the cursor or whose hash holds it.
+	Breadcrumb []string `json:"breadcrumb,omitempty"`
+	// PluginVersion is the gem version the plugin's documentation was
+	// extracted from, e.g. "6.8.0"; empty for older registries.
+	PluginVersion string `json:"pluginVersion,omitempty"`
+}
+
+// pluginDocResult is the documentation of one plugin, independent of the
+// cursor, for a docs panel. Unknown plugins give an empty description and
+// option list.
+type pluginDocResult struct {
+	SectionType     string       `json:"sectionType"`
+	PluginName      string       `json:"pluginName"`
+	Description     string       `json:"description"`
+	LongDescription string       `json:"longDescription,omitempty"`
+	Options         []optionInfo `json:"options"`
 }
 
 type pluginInfo struct {
@@ -31,6 +59,8 @@ type optionInfo struct {
 	Required    bool   `json:"required,omitempty"`
 	Default     string `json:"default,omitempty"`
 	Description string `json:"description,omitempty"`
+	Sensitive   bool   `json:"sensitive,omitempty"` // holds or may embed a secret that shouldn't be hardcoded
+	Source      string `json:"source,omitempty"`    // see optionDoc.Source
 }
 
 // extractWordAtPos returns the identifier word at/around the given cursor position.
@@ -73,6 +103,7 @@ func buildContextInfo(ctx completionContext, source string, pos int) contextInfo
 			Kind:        "section",
 			SectionType: sectionName,
 			Plugins:     getPluginList(ctx.SectionType),
+			Breadcrumb:  ctx.Breadcrumb,
 		}
 
 	case "plugin":
@@ -82,35 +113,134 @@ func buildContextInfo(ctx completionContext, source string, pos int) contextInfo
 			Kind:        "section",
 			SectionType: sectionName,
 			Plugins:     getPluginList(ctx.SectionType),
+			Breadcrumb:  ctx.Breadcrumb,
 		}
 
 	case "option":
-		// Inside a plugin block — list options
-		sectionName := pluginTypeString(ctx.SectionType)
+		// Inside a plugin block — list options. Inside a nested codec block
+		// the codec takes the plugin's place, with section type "codec".
+		sectionName, pluginName := pluginTypeString(ctx.SectionType), ctx.PluginName
+		doc, options := pluginDocumentation(ctx.SectionType, ctx.PluginName)
+		if ctx.CodecName != "" {
+			sectionName, pluginName = "codec", ctx.CodecName
+			doc, options = getPluginDocInfo("codec", pluginName), getCodecOptionList(pluginName)
+		}
 		word := extractWordAtPos(source, pos)
 		result := contextInfoResult{
 			Kind:        "plugin",
 			SectionType: sectionName,
-			PluginName:  ctx.PluginName,
-			PluginDoc:   getPluginDocInfo(sectionName, ctx.PluginName),
+			PluginName:  pluginName,
+			PluginDoc:   doc,
 			OptionName:  word,
-			Options:     getOptionList(ctx.SectionType, ctx.PluginName),
+			Options:     options,
+			Breadcrumb:  ctx.Breadcrumb,
 		}
+		result.PluginVersion = getPluginVersion(sectionName, pluginName)
 		if word != "" {
-			result.OptionDoc = getOptionDocInfo(sectionName, ctx.PluginName, word)
+			result.OptionDoc = getOptionDocInfo(sectionName, pluginName, word)
+		}
+		// Hash keys aren't options: describe the option owning the hash.
+		if doc := getOptionDocInfo(sectionName, pluginName, ctx.HashOption); doc != nil && doc.Type == "hash" {
+			result.HashOption = ctx.HashOption
+			result.OptionName = ctx.HashOption
+			result.OptionDoc = doc
+			return result
 		}
+		if result.OptionDoc != nil {
+			result.Breadcrumb = append(slices.Clip(result.Breadcrumb), word)
+		}
+		result.OptionValueIssue = optionValueIssue(sectionName, pluginName, source, pos)
 		return result
 
 	case "codec":
-		return contextInfoResult{
-			Kind:    "codec",
-			Plugins: getCodecList(),
+		result := contextInfoResult{
+			Kind:       "codec",
+			PluginName: ctx.PluginName,
+			Plugins:    getCodecList(ctx.SectionType, ctx.PluginName),
+			Breadcrumb: ctx.Breadcrumb,
+		}
+		if ctx.SectionType != 0 {
+			result.SectionType = pluginTypeString(ctx.SectionType)
 		}
+		return result
 	}
 
 	return contextInfoResult{Kind: "none"}
 }
 
+// optionAssignmentRegex matches the start of an `name => value` assignment.
+var optionAssignmentRegex = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*|"[^"]*"|'[^']*')\s*=>`)
+
+// optionAssignmentAt returns the text of the `name => value` assignment on
+// the cursor's line: the last one starting before the cursor, or the first.
+func optionAssignmentAt(source string, pos int) string {
+	if pos > len(source) {
+		pos = len(source)
+	}
+	lineStart := strings.LastIndexByte(source[:pos], '\n') + 1
+	lineEnd := strings.IndexByte(source[pos:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(source)
+	} else {
+		lineEnd += pos
+	}
+	line := source[lineStart:lineEnd]
+
+	matches := optionAssignmentRegex.FindAllStringIndex(line, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	i := 0
+	for j, m := range matches {
+		if lineStart+m[0] <= pos {
+			i = j
+		}
+	}
+	end := len(line)
+	if i+1 < len(matches) {
+		end = matches[i+1][0]
+	}
+	return strings.TrimSpace(line[matches[i][0]:end])
+}
+
+// optionValueIssue type-checks the option value on the cursor's line with
+// the same rules as validation. The assignment is parsed on its own inside
+// a stub plugin, so it works while the rest of the config is incomplete;
+// values spanning several lines are not checked.
+func optionValueIssue(sectionType, pluginName, source string, pos int) string {
+	assignment := optionAssignmentAt(source, pos)
+	if assignment == "" {
+		return ""
+	}
+	stub := "filter { stub { " + assignment + " } }"
+	parsed, err := config.Parse("", []byte(stub))
+	if err != nil {
+		return ""
+	}
+	cfg, ok := parsed.(ast.Config)
+	if !ok || len(cfg.Filter) == 0 || len(cfg.Filter[0].BranchOrPlugins) == 0 {
+		return ""
+	}
+	plugin, ok := cfg.Filter[0].BranchOrPlugins[0].(ast.Plugin)
+	if !ok || len(plugin.Attributes) != 1 {
+		return ""
+	}
+
+	attr := plugin.Attributes[0]
+	name := optionName(attr)
+	doc := getOptionDocInfo(sectionType, pluginName, name)
+	if doc == nil {
+		return ""
+	}
+	if msg := valueTypeMismatch(doc.Type, attr); msg != "" {
+		return fmt.Sprintf("option %q %s", name, msg)
+	}
+	if diags := validateEnumValue(attr, name, doc.Type, stub, nil); len(diags) > 0 {
+		return diags[0].Message
+	}
+	return ""
+}
+
 // getPluginList returns a sorted list of plugins for a section type.
 func getPluginList(pt ast.PluginType) []pluginInfo {
 	mu.RLock()
@@ -134,8 +264,10 @@ func getPluginList(pt ast.PluginType) []pluginInfo {
 	return list
 }
 
-// getCodecList returns a sorted list of available codecs.
-func getCodecList() []pluginInfo {
+// availableCodecs returns the sorted codec names usable with a plugin.
+// Every codec is offered for now; this is the place to filter per plugin
+// (e.g. line-oriented codecs only for inputs that read streams).
+func availableCodecs(pt ast.PluginType, pluginName string) []string {
 	mu.RLock()
 	codecs := knownCodecs
 	mu.RUnlock()
@@ -144,26 +276,55 @@ func getCodecList() []pluginInfo {
 		return nil
 	}
 
-	list := make([]pluginInfo, 0, len(codecs))
+	names := make([]string, 0, len(codecs))
 	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getCodecList returns a sorted list of codecs available to a plugin.
+func getCodecList(pt ast.PluginType, pluginName string) []pluginInfo {
+	names := availableCodecs(pt, pluginName)
+	if names == nil {
+		return nil
+	}
+
+	list := make([]pluginInfo, 0, len(names))
+	for _, name := range names {
 		info := pluginInfo{Name: name}
 		if doc := getPluginDocInfo("codec", name); doc != nil {
 			info.Description = doc.Description
 		}
 		list = append(list, info)
 	}
-	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
 	return list
 }
 
+// pluginDocumentation returns a plugin's doc (nil if the registry has none)
+// and its option list, as shown by the sidebar and the docs panel.
+func pluginDocumentation(pt ast.PluginType, pluginName string) (*pluginDoc, []optionInfo) {
+	return getPluginDocInfo(pluginTypeString(pt), pluginName), getOptionList(pt, pluginName)
+}
+
 // getOptionList returns a sorted list of options for a plugin.
 func getOptionList(pt ast.PluginType, pluginName string) []optionInfo {
-	known := getPluginOptions(pt, pluginName)
+	return optionList(pluginTypeString(pt), pluginName, getPluginOptions(pt, pluginName))
+}
+
+// getCodecOptionList returns a sorted list of options for a codec.
+func getCodecOptionList(codecName string) []optionInfo {
+	return optionList("codec", codecName, getCodecOptions(codecName))
+}
+
+// optionList documents the known options of a plugin or codec, required
+// ones first. It returns nil when known is nil.
+func optionList(sectionName, pluginName string, known map[string]bool) []optionInfo {
 	if known == nil {
 		return nil
 	}
 
-	sectionName := pluginTypeString(pt)
 	list := make([]optionInfo, 0, len(known))
 	for name := range known {
 		info := optionInfo{Name: name}
@@ -172,6 +333,8 @@ func getOptionList(pt ast.PluginType, pluginName string) []optionInfo {
 			info.Required = doc.Required
 			info.Default = doc.Default
 			info.Description = doc.Description
+			info.Sensitive = doc.Sensitive
+			info.Source = doc.Source
 		}
 		list = append(list, info)
 	}
@@ -196,8 +359,54 @@ func getContextInfo(this js.Value, args []js.Value) interface{} {
 	pos := args[1].Int()
 
 	ctx := detectStructuralContext(source, pos)
+	if valueCtx := detectContext(source, pos); valueCtx.Kind == "codec" {
+		valueCtx.Breadcrumb = ctx.Breadcrumb
+		ctx = valueCtx
+	}
 	result := buildContextInfo(ctx, source, pos)
 
 	b, _ := json.Marshal(result)
 	return string(b)
 }
+
+// getPluginDoc is the WASM entry point for the docs panel. Args:
+// sectionType, pluginName. Returns a pluginDocResult.
+func getPluginDoc(this js.Value, args []js.Value) interface{} {
+	result := pluginDocResult{Options: []optionInfo{}}
+	if len(args) >= 2 {
+		result.SectionType = args[0].String()
+		result.PluginName = args[1].String()
+	}
+
+	if pt, ok := pluginTypeMap[result.SectionType]; ok {
+		doc, options := pluginDocumentation(pt, result.PluginName)
+		if doc != nil {
+			result.Description = doc.Description
+			result.LongDescription = doc.LongDescription
+		}
+		if options != nil {
+			result.Options = options
+		}
+	}
+
+	b, _ := json.Marshal(result)
+	return string(b)
+}
+
+// getAvailableCodecs is the WASM entry point listing the codecs usable with
+// a plugin. Args: sectionType, pluginName. Returns {"codecs": [...]}.
+func getAvailableCodecs(this js.Value, args []js.Value) interface{} {
+	var pt ast.PluginType
+	pluginName := ""
+	if len(args) >= 2 {
+		pt = pluginTypeMap[args[0].String()]
+		pluginName = args[1].String()
+	}
+
+	codecs := getCodecList(pt, pluginName)
+	if codecs == nil {
+		codecs = []pluginInfo{}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"codecs": codecs})
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+const (
+	docsBaseURL          = "https://www.elastic.co/guide/en/logstash"
+	versionedDocsBaseURL = "https://www.elastic.co/guide/en/logstash-versioned-plugins/current"
+)
+
+// docURL builds the elastic.co reference URL for a plugin, anchored at
+// optionName when one is provided. When gemVersion, the plugin gem the
+// registry schema came from, is known the link goes to that release's page
+// in the versioned plugin docs; otherwise to the page in the Logstash
+// reference for version. sectionType is "input", "filter", "output" or "codec".
+// Examples:
+//
+//	https://www.elastic.co/guide/en/logstash/8.19/plugins-filters-grok.html#plugins-filters-grok-match
+//	https://www.elastic.co/guide/en/logstash-versioned-plugins/current/v4.4.3-plugins-filters-grok.html#v4.4.3-plugins-filters-grok-match
+func docURL(version, gemVersion, sectionType, pluginName, optionName string) string {
+	page := "plugins-" + sectionType + "s-" + pluginName
+	base := docsBaseURL + "/" + version
+	if gemVersion != "" {
+		page = "v" + gemVersion + "-" + page
+		base = versionedDocsBaseURL
+	}
+	url := base + "/" + page + ".html"
+	if optionName != "" {
+		url += "#" + page + "-" + optionName
+	}
+	return url
+}
+
+// isKnownDocTarget reports whether the plugin (or codec) exists in the
+// active registry, so we never hand out links to pages that don't exist.
+func isKnownDocTarget(sectionType, pluginName string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if sectionType == "codec" {
+		return knownCodecs[pluginName]
+	}
+	pt, ok := pluginTypeMap[sectionType]
+	if !ok {
+		return false
+	}
+	return knownPlugins[pt][pluginName]
+}
+
+// getDocURL is the WASM entry point returning the upstream docs URL for a
+// plugin or plugin option, versioned to the plugin's gem when the registry
+// records it. Args: sectionType, pluginName, optionName (optional).
+// Returns {"url": "..."}; url is empty for unknown plugins.
+func getDocURL(this js.Value, args []js.Value) interface{} {
+	result := map[string]string{"url": ""}
+	if len(args) < 2 {
+		b, _ := json.Marshal(result)
+		return string(b)
+	}
+
+	sectionType := args[0].String()
+	pluginName := args[1].String()
+	optionName := ""
+	if len(args) > 2 && args[2].Type() == js.TypeString {
+		optionName = args[2].String()
+	}
+
+	if isKnownDocTarget(sectionType, pluginName) {
+		mu.RLock()
+		version := currentVersion
+		mu.RUnlock()
+		gemVersion := getPluginVersion(sectionType, pluginName)
+		result["url"] = docURL(version, gemVersion, sectionType, pluginName, optionName)
+	}
+
+	b, _ := json.Marshal(result)
+	return string(b)
+}
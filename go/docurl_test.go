@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestDocURL(t *testing.T) {
+	tests := []struct {
+		version, gemVersion, sectionType, plugin, option string
+		want                                             string
+	}{
+		{"8.19", "", "filter", "grok", "",
+			"https://www.elastic.co/guide/en/logstash/8.19/plugins-filters-grok.html"},
+		{"8.19", "", "filter", "grok", "match",
+			"https://www.elastic.co/guide/en/logstash/8.19/plugins-filters-grok.html#plugins-filters-grok-match"},
+		{"8.19", "4.4.3", "filter", "grok", "",
+			"https://www.elastic.co/guide/en/logstash-versioned-plugins/current/v4.4.3-plugins-filters-grok.html"},
+		{"8.19", "4.4.3", "filter", "grok", "match",
+			"https://www.elastic.co/guide/en/logstash-versioned-plugins/current/v4.4.3-plugins-filters-grok.html#v4.4.3-plugins-filters-grok-match"},
+		{"8.15", "3.1.0", "codec", "json", "charset",
+			"https://www.elastic.co/guide/en/logstash-versioned-plugins/current/v3.1.0-plugins-codecs-json.html#v3.1.0-plugins-codecs-json-charset"},
+	}
+	for _, tt := range tests {
+		if got := docURL(tt.version, tt.gemVersion, tt.sectionType, tt.plugin, tt.option); got != tt.want {
+			t.Errorf("docURL(%q, %q, %q, %q, %q) = %q, want %q",
+				tt.version, tt.gemVersion, tt.sectionType, tt.plugin, tt.option, got, tt.want)
+		}
+	}
+}
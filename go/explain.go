@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+)
+
+// diagnosticExplanation is the extended help for the diagnostic at a
+// position. Suggestions lists what would be valid instead: the plugin's
+// options for an unknown option, the section's plugins for an unknown
+// plugin, the codecs for an unknown codec. Other diagnostics only get
+// their message; positions without a diagnostic get an empty Title.
+type diagnosticExplanation struct {
+	Title       string       `json:"title"`
+	Code        string       `json:"code,omitempty"`
+	Message     string       `json:"message,omitempty"`
+	Suggestions []pluginInfo `json:"suggestions"`
+}
+
+// explainDiagnostic validates the source and explains the first diagnostic
+// whose range contains offset.
+func explainDiagnostic(source string, offset int) diagnosticExplanation {
+	result := diagnosticExplanation{Suggestions: []pluginInfo{}}
+	var diag *Diagnostic
+	for _, d := range parseAndValidate(source, parseOptions{}).Diagnostics {
+		if d.From <= offset && offset <= d.To {
+			diag = &d
+			break
+		}
+	}
+	if diag == nil {
+		return result
+	}
+	result.Title = diag.Message
+	result.Code = diag.Code
+	result.Message = diag.Message
+
+	// The diagnostic starts at the plugin name, or at the option key for
+	// options and codecs, where the structural context names the block.
+	ctx := detectStructuralContext(source, diag.From)
+	section := pluginTypeString(ctx.SectionType)
+	switch diag.Code {
+	case codeUnknownOption:
+		// Inside a nested codec block the options are the codec's.
+		title := fmt.Sprintf("Options of the %s %s plugin", ctx.PluginName, section)
+		options := getOptionList(ctx.SectionType, ctx.PluginName)
+		if ctx.CodecName != "" {
+			title = fmt.Sprintf("Options of the %s codec", ctx.CodecName)
+			options = getCodecOptionList(ctx.CodecName)
+		}
+		if options != nil {
+			result.Title = title
+			for _, o := range options {
+				result.Suggestions = append(result.Suggestions, pluginInfo{Name: o.Name, Description: o.Description})
+			}
+		}
+	case codeUnknownPlugin:
+		if plugins := getPluginList(ctx.SectionType); plugins != nil {
+			result.Title = fmt.Sprintf("Plugins available in %s", section)
+			result.Suggestions = plugins
+		}
+	case codeUnknownCodec:
+		if codecs := getCodecList(ctx.SectionType, ctx.PluginName); codecs != nil {
+			result.Title = fmt.Sprintf("Codecs available to the %s %s plugin", ctx.PluginName, section)
+			result.Suggestions = codecs
+		}
+	}
+	return result
+}
+
+// getExplanation is the WASM entry point explaining the diagnostic at a
+// position. Args: source, offset. Returns a diagnosticExplanation.
+func getExplanation(this js.Value, args []js.Value) interface{} {
+	result := diagnosticExplanation{Suggestions: []pluginInfo{}}
+	if len(args) >= 2 {
+		result = explainDiagnostic(args[0].String(), args[1].Int())
+	}
+	b, _ := json.Marshal(result)
+	return string(b)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"syscall/js"
+)
+
+// foldRange is a foldable block: From is just past its {, To is at its }.
+type foldRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// bracePair is a matched { } in the source.
+type bracePair struct {
+	Open  int // offset of the {
+	Close int // offset of the matching }
+}
+
+// matchBraces pairs up the braces in the source like findSectionRanges
+// does, skipping those inside strings, comments and condition regexes
+// (/.../ after =~ or !~). Pairs are ordered by Open; the offsets of braces
+// without a partner are returned in order as unmatched.
+func matchBraces(source string) (matched []bracePair, unmatched []int) {
+	var pairs []bracePair
+	var stack []int // indexes into pairs of the open {
+
+	i := 0
+	for i < len(source) {
+		if end := skipLiteral(source, i); end > i {
+			i = end
+			continue
+		}
+		switch source[i] {
+		case '{':
+			stack = append(stack, len(pairs))
+			pairs = append(pairs, bracePair{Open: i, Close: -1})
+
+		case '}':
+			if len(stack) > 0 {
+				pairs[stack[len(stack)-1]].Close = i
+				stack = stack[:len(stack)-1]
+			} else {
+				unmatched = append(unmatched, i)
+			}
+		}
+		i++
+	}
+
+	for _, p := range pairs {
+		if p.Close >= 0 {
+			matched = append(matched, p)
+		} else {
+			unmatched = append(unmatched, p.Open)
+		}
+	}
+	slices.Sort(unmatched)
+	return matched, unmatched
+}
+
+// braceDiagnostics reports each unmatched brace at its offset. The parser
+// only notices these where parsing fails, often far from the brace.
+func braceDiagnostics(input string) []Diagnostic {
+	_, unmatched := matchBraces(input)
+	var diags []Diagnostic
+	for _, offset := range unmatched {
+		msg := `unexpected "}"`
+		if input[offset] == '{' {
+			msg = `unmatched "{" opened here`
+		}
+		diags = append(diags, Diagnostic{
+			From: offset, To: offset + 1, Severity: "error", Message: msg, Code: codeSyntaxError,
+		})
+	}
+	return diags
+}
+
+// skipLiteral returns the offset just past the string, condition regex or
+// comment starting at offset i, or i when none starts there. A comment ends
+// before its newline; an unterminated literal runs to the end of source.
+func skipLiteral(source string, i int) int {
+	ch := source[i]
+	switch {
+	case ch == '#':
+		for i < len(source) && source[i] != '\n' {
+			i++
+		}
+		return i
+
+	case ch == '"' || ch == '\'' || ch == '/' && regexFollowsOperator(source, i):
+		i++
+		for i < len(source) && source[i] != ch {
+			if source[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		return min(i+1, len(source))
+	}
+	return i
+}
+
+// regexFollowsOperator reports whether the / at offset i opens a regex
+// literal, i.e. follows =~ or !~.
+func regexFollowsOperator(source string, i int) bool {
+	j := i - 1
+	for j >= 0 && isBlank(source[j]) {
+		j--
+	}
+	return j >= 1 && source[j] == '~' && (source[j-1] == '=' || source[j-1] == '!')
+}
+
+// foldRanges returns a fold range for every brace-delimited block: sections,
+// plugins, conditionals and hashes.
+func foldRanges(source string) []foldRange {
+	pairs, _ := matchBraces(source)
+	ranges := make([]foldRange, 0, len(pairs))
+	for _, p := range pairs {
+		ranges = append(ranges, foldRange{From: p.Open + 1, To: p.Close})
+	}
+	return ranges
+}
+
+// getFoldRanges is the WASM entry point for code folding. Args: source.
+// Returns [{from, to}, ...].
+func getFoldRanges(this js.Value, args []js.Value) interface{} {
+	ranges := []foldRange{}
+	if len(args) >= 1 {
+		ranges = foldRanges(args[0].String())
+	}
+	b, _ := json.Marshal(ranges)
+	return string(b)
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	config "github.com/breml/logstash-config"
+	"github.com/breml/logstash-config/ast"
+)
+
+// formatResult is the JSON response of formatLogstashConfig.
+type formatResult struct {
+	OK        bool   `json:"ok"`
+	Formatted string `json:"formatted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// formatOptions are the optional settings accepted by formatLogstashConfig.
+type formatOptions struct {
+	Quotes string // "double" or "single" to normalize quoted strings; anything else keeps them
+	Style  string // "compact" joins short plugins onto one line; anything else is expanded
+	Indent string // one level of indentation; empty for two spaces
+}
+
+const (
+	// compactLineWidth is the longest line, indentation included, that
+	// compact formatting joins a plugin into.
+	compactLineWidth = 80
+	// tabWidth is the columns a tab indent counts for against compactLineWidth.
+	tabWidth = 4
+	// maxIndentWidth is the most spaces accepted as the indent option.
+	maxIndentWidth = 8
+)
+
+// indentUnit returns the indentation for the formatter's indent option: a
+// number of spaces from 1 to maxIndentWidth, or "tab".
+func indentUnit(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case int:
+		if v >= 1 && v <= maxIndentWidth {
+			return strings.Repeat(" ", v), nil
+		}
+	case string:
+		if v == "tab" {
+			return "\t", nil
+		}
+	}
+	return "", fmt.Errorf("indent must be 1 to %d spaces or \"tab\", got %v", maxIndentWidth, v)
+}
+
+// formatConfig re-serializes a config with one attribute per line and
+// normalized " => " spacing, indented by opts.Indent (two spaces by
+// default). The ast String() methods cover every node type and keep
+// comments. On a parse error the input is returned unchanged. The compact
+// style then joins plugins that fit on a line (see compactPlugins).
+func formatConfig(input string, opts formatOptions) formatResult {
+	parsed, err := config.Parse("", []byte(input))
+	if err != nil {
+		msg := strings.Join(strings.Fields(err.Error()), " ")
+		return formatResult{OK: false, Formatted: input, Error: msg}
+	}
+	cfg, ok := parsed.(ast.Config)
+	if !ok {
+		return formatResult{OK: false, Formatted: input, Error: "unexpected parser result"}
+	}
+	switch opts.Quotes {
+	case "double":
+		normalizeQuotes(cfg, ast.DoubleQuoted)
+	case "single":
+		normalizeQuotes(cfg, ast.SingleQuoted)
+	}
+	unit := opts.Indent
+	if unit == "" {
+		unit = "  "
+	}
+	formatted := cfg.String()
+	if opts.Style == "compact" {
+		// Keep the expanded form should joining ever break the syntax.
+		compact := compactPlugins(cfg, formatted, unit)
+		if _, err := config.Parse("", []byte(compact)); err == nil {
+			formatted = compact
+		}
+	}
+	return formatResult{OK: true, Formatted: reindent(formatted, unit)}
+}
+
+// reindent replaces the two-space indentation the ast String() methods
+// produce with unit, one unit per two spaces. Strings, regexes and comments
+// are skipped with the scanner matchBraces uses, so lines continuing a
+// string that spans lines are kept as they are.
+func reindent(formatted, unit string) string {
+	if unit == "  " {
+		return formatted
+	}
+	var out strings.Builder
+	i := 0
+	for i < len(formatted) {
+		if i == 0 || formatted[i-1] == '\n' {
+			n := 0
+			for i+n < len(formatted) && formatted[i+n] == ' ' {
+				n++
+			}
+			out.WriteString(strings.Repeat(unit, n/2))
+			if i += n; i == len(formatted) {
+				break
+			}
+		}
+		end := max(skipLiteral(formatted, i), i+1)
+		out.WriteString(formatted[i:end])
+		i = end
+	}
+	return out.String()
+}
+
+// compactPlugins rewrites formatted, the expanded rendering of cfg, with
+// each plugin that has no comments inside and fits within compactLineWidth
+// on one line, e.g. mutate { add_tag => ["a", "b"] }. Plugins are found in
+// order by their expanded text indented to their nesting depth; the line
+// width counts that depth in units of unit, the indentation reindent will
+// apply.
+func compactPlugins(cfg ast.Config, formatted, unit string) string {
+	unitWidth := len(strings.ReplaceAll(unit, "\t", strings.Repeat(" ", tabWidth)))
+	var out strings.Builder
+	rest := formatted
+	var visit func(block []ast.BranchOrPlugin, depth int)
+	visit = func(block []ast.BranchOrPlugin, depth int) {
+		for _, bop := range block {
+			switch node := bop.(type) {
+			case ast.Plugin:
+				indent := strings.Repeat("  ", depth)
+				expanded := indentLines(node.String(), indent)
+				i := strings.Index(rest, expanded)
+				if i < 0 {
+					continue
+				}
+				out.WriteString(rest[:i])
+				if line, ok := compactPlugin(node); ok && depth*unitWidth+len(line) <= compactLineWidth {
+					out.WriteString(indentLines(node.Comment.String(), indent) + indent + line)
+				} else {
+					out.WriteString(expanded)
+				}
+				rest = rest[i+len(expanded):]
+			case ast.Branch:
+				visit(node.IfBlock.Block, depth+1)
+				for _, elseIf := range node.ElseIfBlock {
+					visit(elseIf.Block, depth+1)
+				}
+				visit(node.ElseBlock.Block, depth+1)
+			}
+		}
+	}
+	for _, sections := range [][]ast.PluginSection{cfg.Input, cfg.Filter, cfg.Output} {
+		for _, section := range sections {
+			visit(section.BranchOrPlugins, 1)
+		}
+	}
+	out.WriteString(rest)
+	return out.String()
+}
+
+// indentLines prefixes every line of s that isn't blank with indent.
+func indentLines(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.TrimLeft(l, " ") == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = indent + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// compactPlugin renders a plugin on one line, or reports false when it has
+// comments inside or a value that spans lines. Comments above the plugin
+// are left to the caller.
+func compactPlugin(p ast.Plugin) (string, bool) {
+	if len(p.FooterComment) > 0 {
+		return "", false
+	}
+	var attrs []string
+	for _, attr := range p.Attributes {
+		if attr == nil {
+			continue
+		}
+		if attr.CommentBlock() != "" {
+			return "", false
+		}
+		value, ok := compactValue(attr)
+		if !ok {
+			return "", false
+		}
+		attrs = append(attrs, attr.Name()+" => "+value)
+	}
+	if len(attrs) == 0 {
+		return p.Name() + " {}", true
+	}
+	return p.Name() + " { " + strings.Join(attrs, " ") + " }", true
+}
+
+// compactValue renders a value on one line: arrays as [a, b] and hashes as
+// { "k" => v "k2" => v2 }. It reports false for values with comments or
+// strings spanning lines.
+func compactValue(value ast.Attribute) (string, bool) {
+	var parts []string
+	switch v := value.(type) {
+	case ast.ArrayAttribute:
+		if len(v.FooterComment) > 0 {
+			return "", false
+		}
+		for _, a := range v.Attributes {
+			if a == nil {
+				continue
+			}
+			s, ok := compactValue(a)
+			if !ok || a.CommentBlock() != "" {
+				return "", false
+			}
+			parts = append(parts, s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", true
+	case ast.HashAttribute:
+		if len(v.FooterComment) > 0 {
+			return "", false
+		}
+		for _, entry := range v.Entries {
+			s, ok := compactValue(entry.Value)
+			if !ok || len(entry.Comment) > 0 {
+				return "", false
+			}
+			parts = append(parts, entry.Name()+" => "+s)
+		}
+		if len(parts) == 0 {
+			return "{}", true
+		}
+		return "{ " + strings.Join(parts, " ") + " }", true
+	}
+	s := value.ValueString()
+	return s, !strings.Contains(s, "\n")
+}
+
+// normalizeQuotes rewrites quoted strings in option values, hash keys and
+// conditions to use the target quotes, in place. Strings containing the
+// target quote character keep theirs, as do barewords and the options of
+// nested codec blocks (which the ast doesn't expose).
+func normalizeQuotes(cfg ast.Config, target ast.StringAttributeType) {
+	q := quoteNormalizer{target: target}
+	for _, sections := range [][]ast.PluginSection{cfg.Input, cfg.Filter, cfg.Output} {
+		for _, section := range sections {
+			q.block(section.BranchOrPlugins)
+		}
+	}
+}
+
+type quoteNormalizer struct {
+	target ast.StringAttributeType
+}
+
+func (q quoteNormalizer) block(block []ast.BranchOrPlugin) {
+	for _, bop := range block {
+		switch node := bop.(type) {
+		case ast.Plugin:
+			for i, attr := range node.Attributes {
+				node.Attributes[i] = q.attribute(attr)
+			}
+		case ast.Branch:
+			q.condition(node.IfBlock.Condition)
+			q.block(node.IfBlock.Block)
+			for _, elseIf := range node.ElseIfBlock {
+				q.condition(elseIf.Condition)
+				q.block(elseIf.Block)
+			}
+			q.block(node.ElseBlock.Block)
+		}
+	}
+}
+
+func (q quoteNormalizer) attribute(attr ast.Attribute) ast.Attribute {
+	switch v := attr.(type) {
+	case ast.StringAttribute:
+		return q.string(v)
+	case ast.ArrayAttribute:
+		for i, a := range v.Attributes {
+			v.Attributes[i] = q.attribute(a)
+		}
+	case ast.HashAttribute:
+		for i, entry := range v.Entries {
+			if key, ok := entry.Key.(ast.StringAttribute); ok {
+				v.Entries[i].Key = q.string(key)
+			}
+			v.Entries[i].Value = q.attribute(entry.Value)
+		}
+	}
+	return attr
+}
+
+func (q quoteNormalizer) condition(cond ast.Condition) {
+	for i, expr := range cond.Expression {
+		switch e := expr.(type) {
+		case ast.ConditionExpression:
+			q.condition(e.Condition)
+		case ast.NegativeConditionExpression:
+			q.condition(e.Condition)
+		case ast.CompareExpression:
+			e.LValue, e.RValue = q.rvalue(e.LValue), q.rvalue(e.RValue)
+			cond.Expression[i] = e
+		case ast.RegexpExpression:
+			e.LValue = q.rvalue(e.LValue)
+			cond.Expression[i] = e
+		case ast.InExpression:
+			e.LValue, e.RValue = q.rvalue(e.LValue), q.rvalue(e.RValue)
+			cond.Expression[i] = e
+		case ast.NotInExpression:
+			e.LValue, e.RValue = q.rvalue(e.LValue), q.rvalue(e.RValue)
+			cond.Expression[i] = e
+		case ast.RvalueExpression:
+			e.RValue = q.rvalue(e.RValue)
+			cond.Expression[i] = e
+		}
+	}
+}
+
+func (q quoteNormalizer) rvalue(v ast.Rvalue) ast.Rvalue {
+	switch rv := v.(type) {
+	case ast.StringAttribute:
+		return q.string(rv)
+	case ast.ArrayAttribute:
+		q.attribute(rv)
+	}
+	return v
+}
+
+// string returns sa with the target quotes, or unchanged when it is a
+// bareword or its value contains the target quote character or an escaped
+// quote of its own kind (\' in 'it\'s'), whose escaping would change.
+func (q quoteNormalizer) string(sa ast.StringAttribute) ast.StringAttribute {
+	sat := sa.StringAttributeType()
+	if sat == ast.Bareword || sat == q.target ||
+		strings.Contains(sa.Value(), q.target.String()) || strings.Contains(sa.Value(), `\`+sat.String()) {
+		return sa
+	}
+	out := ast.NewStringAttribute(sa.Name(), sa.Value(), q.target)
+	out.Start = sa.Start
+	out.Comment = sa.Comment
+	return out
+}
+
+// formatLogstashConfig is the WASM entry point for the formatter.
+// Args: source, options ({ quotes: "double" | "single" | "preserve",
+// style: "expanded" | "compact", indent: 1-8 | "tab" }).
+// Returns {ok, formatted, error}; an invalid indent is an error.
+func formatLogstashConfig(this js.Value, args []js.Value) interface{} {
+	result := formatResult{OK: false, Error: "no input provided"}
+	if len(args) > 0 {
+		var opts formatOptions
+		var err error
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			if quotes := args[1].Get("quotes"); quotes.Type() == js.TypeString {
+				opts.Quotes = quotes.String()
+			}
+			if style := args[1].Get("style"); style.Type() == js.TypeString {
+				opts.Style = style.String()
+			}
+			switch indent := args[1].Get("indent"); indent.Type() {
+			case js.TypeNumber:
+				opts.Indent, err = indentUnit(indent.Int())
+			case js.TypeString:
+				opts.Indent, err = indentUnit(indent.String())
+			}
+		}
+		if err != nil {
+			result = formatResult{OK: false, Formatted: args[0].String(), Error: err.Error()}
+		} else {
+			result = formatConfig(args[0].String(), opts)
+		}
+	}
+	b, _ := json.Marshal(result)
+	return string(b)
+}
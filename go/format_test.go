@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	config "github.com/breml/logstash-config"
+)
+
+// formatSamples are configs the formatter must round-trip.
+var formatSamples = []string{
+	`input { stdin { } beats { port => 5044 ssl_enabled => true } }`,
+	`filter {
+  # a comment
+  if [type] == "syslog" {
+    grok { match => { "message" => "%{SYSLOGLINE}" } }
+    date { match => [ "timestamp", "MMM  d HH:mm:ss" ] }
+  } else if [a] =~ /x/ { drop { } }
+  mutate { add_field => { "a" => "multi
+line" } add_tag => ["x", "y"] }
+}`,
+	`output { elasticsearch { hosts => ["http://localhost:9200"] index => "logs-%{+YYYY.MM.dd}" codec => json { charset => "UTF-8" } } stdout { codec => rubydebug } }`,
+}
+
+// mustFormat formats src with opts, failing the test unless it parses again.
+func mustFormat(t *testing.T, src string, opts formatOptions) string {
+	t.Helper()
+	result := formatConfig(src, opts)
+	if !result.OK {
+		t.Fatalf("format %+v of %q: %s", opts, src, result.Error)
+	}
+	if _, err := config.Parse("", []byte(result.Formatted)); err != nil {
+		t.Fatalf("format %+v output doesn't parse: %v\n%s", opts, err, result.Formatted)
+	}
+	return result.Formatted
+}
+
+func TestFormatStyles(t *testing.T) {
+	for _, src := range formatSamples {
+		expanded := mustFormat(t, src, formatOptions{})
+		if styled := mustFormat(t, src, formatOptions{Style: "expanded"}); styled != expanded {
+			t.Errorf("expanded style differs from the default:\n%s\n%s", styled, expanded)
+		}
+		compact := mustFormat(t, src, formatOptions{Style: "compact"})
+		if strings.Count(compact, "\n") > strings.Count(expanded, "\n") {
+			t.Errorf("compact is longer than expanded:\n%s", compact)
+		}
+		// Both styles hold the same config.
+		if back := mustFormat(t, compact, formatOptions{}); back != expanded {
+			t.Errorf("compact output expands to\n%s\nwant\n%s", back, expanded)
+		}
+		if again := mustFormat(t, compact, formatOptions{Style: "compact"}); again != compact {
+			t.Errorf("compact formatting isn't stable:\n%s\n%s", again, compact)
+		}
+	}
+}
+
+func TestFormatCompactJoinsShortPlugins(t *testing.T) {
+	got := mustFormat(t, "filter {\n  mutate {\n    add_tag => [\"a\"]\n  }\n}\n", formatOptions{Style: "compact"})
+	if !strings.Contains(got, `mutate { add_tag => ["a"] }`) {
+		t.Errorf("short plugin not joined:\n%s", got)
+	}
+}
+
+func TestIndentUnit(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+		ok   bool
+	}{
+		{1, " ", true},
+		{4, "    ", true},
+		{8, "        ", true},
+		{"tab", "\t", true},
+		{0, "", false},
+		{9, "", false},
+		{"spaces", "", false},
+		{2.5, "", false},
+	}
+	for _, tt := range tests {
+		got, err := indentUnit(tt.v)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("indentUnit(%v) = %q, %v; want %q, ok %v", tt.v, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatIndent(t *testing.T) {
+	for _, unit := range []string{" ", "    ", "        ", "\t"} {
+		for _, style := range []string{"expanded", "compact"} {
+			for _, src := range formatSamples {
+				got := mustFormat(t, src, formatOptions{Style: style, Indent: unit})
+				// Same config as the default two-space form.
+				if back, want := mustFormat(t, got, formatOptions{}), mustFormat(t, src, formatOptions{}); back != want {
+					t.Errorf("indent %q %s: reformats to\n%s\nwant\n%s", unit, style, back, want)
+				}
+				if style == "expanded" && strings.Contains(src, "grok") && !strings.Contains(got, "\n"+unit+unit+"grok {") {
+					t.Errorf("indent %q: grok not two levels deep:\n%s", unit, got)
+				}
+			}
+		}
+	}
+	// A string continued on the next line keeps its text as written.
+	got := mustFormat(t, "filter { mutate { add_field => { \"a\" => \"multi\n  line\" } } }", formatOptions{Indent: "\t"})
+	if !strings.Contains(got, "\"multi\n  line\"") {
+		t.Errorf("multi-line string reindented:\n%s", got)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+// grokPatterns is a curated list of commonly used patterns shipped with
+// Logstash's grok filter (the legacy and ECS pattern sets share these names).
+var grokPatterns = []string{
+	"BASE10NUM", "BASE16NUM", "COMBINEDAPACHELOG", "COMMONAPACHELOG",
+	"DATA", "DATE", "DATESTAMP", "DATE_EU", "DATE_US", "DAY",
+	"EMAILADDRESS", "GREEDYDATA", "HOSTNAME", "HOSTPORT", "HOUR",
+	"HTTPDATE", "HTTPDUSER", "INT", "IP", "IPORHOST", "IPV4", "IPV6",
+	"ISO8601_TIMEZONE", "LOGLEVEL", "MAC", "MINUTE", "MONTH", "MONTHDAY",
+	"MONTHNUM", "NONNEGINT", "NOTSPACE", "NUMBER", "PATH", "POSINT",
+	"QS", "QUOTEDSTRING", "SECOND", "SPACE", "SYSLOGBASE", "SYSLOGHOST",
+	"SYSLOGPROG", "SYSLOGTIMESTAMP", "TIME", "TIMESTAMP_ISO8601", "TZ",
+	"UNIXPATH", "URI", "URIHOST", "URIPARAM", "URIPATH", "URIPROTO",
+	"USER", "USERNAME", "UUID", "WORD", "YEAR",
+}
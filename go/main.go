@@ -2,10 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall/js"
+	"time"
+	"unicode/utf8"
 
 	config "github.com/breml/logstash-config"
 	"github.com/breml/logstash-config/ast"
@@ -16,100 +21,601 @@ type Diagnostic struct {
 	To       int    `json:"to"`
 	Severity string `json:"severity"`
 	Message  string `json:"message"`
+	Code     string `json:"code,omitempty"`
+	Fix      *Fix   `json:"fix,omitempty"`
+
+	// 1-based line and column (in characters) of From and To, for reports
+	// outside the editor; see lineIndex.
+	FromLine int `json:"fromLine,omitempty"`
+	FromCol  int `json:"fromCol,omitempty"`
+	ToLine   int `json:"toLine,omitempty"`
+	ToCol    int `json:"toCol,omitempty"`
+}
+
+// lineIndex holds the offset at which each line of a source starts, to turn
+// offsets into lines and columns without rescanning the source.
+type lineIndex struct {
+	input  string
+	starts []int
+}
+
+func newLineIndex(input string) lineIndex {
+	starts := []int{0}
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return lineIndex{input: input, starts: starts}
+}
+
+// position returns the 1-based line and column of offset.
+func (ix lineIndex) position(offset int) (line, col int) {
+	offset = max(0, min(offset, len(ix.input)))
+	line = sort.Search(len(ix.starts), func(i int) bool { return ix.starts[i] > offset })
+	start := ix.starts[line-1]
+	return line, utf8.RuneCountInString(ix.input[start:offset]) + 1
+}
+
+// locate sets the line and column fields of d.
+func (ix lineIndex) locate(d *Diagnostic) {
+	d.FromLine, d.FromCol = ix.position(d.From)
+	d.ToLine, d.ToCol = ix.position(d.To)
+}
+
+// locateAll sets the line and column fields of every diagnostic.
+func (ix lineIndex) locateAll(diags []Diagnostic) {
+	for i := range diags {
+		ix.locate(&diags[i])
+	}
+}
+
+// Diagnostic codes identify the check that produced a diagnostic.
+const (
+	codeSyntaxError      = "syntax-error"
+	codeUnknownPlugin    = "unknown-plugin"
+	codeUnknownCodec     = "unknown-codec"
+	codeUnknownOption    = "unknown-option"
+	codeQuotedOption     = "quoted-option"
+	codeInvalidValue     = "invalid-value"
+	codeRubyEventAPI     = "ruby-event-api"
+	codeDuplicateSection = "duplicate-section"
+	codeAddFieldString   = "add-field-string"
+	codeConflictingOpts  = "conflicting-options"
+	codeNoopOption       = "noop-option"
+	codeMissingOption    = "missing-option"
+	codeRegexOnNumber    = "regex-on-number"
+	codeGrokBacktracking = "grok-backtracking"
+	codeTypeMismatch     = "type-mismatch"
+	codeDeprecatedOption = "deprecated-option"
+	codeFieldReference   = "field-reference"
+	codeMissingID        = "missing-id"
+	codeDuplicateID      = "duplicate-id"
+	codeUnknownPattern   = "unknown-grok-pattern"
+	codeSprintfReference = "sprintf-reference"
+	codeHardcodedSecret  = "hardcoded-secret"
+	codeStyle            = "style"
+)
+
+// Fix is a suggested edit that resolves a diagnostic: replace [From, To)
+// with Insert.
+type Fix struct {
+	Label  string `json:"label"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Insert string `json:"insert"`
 }
 
 type ParseResult struct {
-	OK          bool        `json:"ok"`
-	Diagnostics []Diagnostic `json:"diagnostics"`
-	Farthest    *Diagnostic  `json:"farthest"`
+	OK            bool          `json:"ok"`
+	Diagnostics   []Diagnostic  `json:"diagnostics"`
+	Farthest      *farthest     `json:"farthest"`
+	Timings       *parseTimings `json:"timings,omitempty"`
+	MultiPipeline bool          `json:"multiPipeline,omitempty"` // input was validated as several pipelines
+	Version       string        `json:"version"`                 // registry version validated against
+	KnownPlugins  int           `json:"knownPlugins"`            // input, filter and output plugins in that registry
+	Summary       string        `json:"summary"`                 // e.g. "3 errors, 2 warnings" or "valid"
+}
+
+// parseOptions are the optional settings accepted by parseLogstashConfig.
+type parseOptions struct {
+	Timings   bool   // include a timing breakdown in the result (debugging aid)
+	Profile   string // lint profile name, see lintProfiles
+	AllErrors bool   // on parse failure, re-parse each section to report every broken one
+	LintIDs   bool   // report plugins without an id (opt-in, see validatePluginIDs)
+	Strict    bool   // report unknown plugins, codecs and options as errors, not warnings
+	Style     bool   // note discouraged but valid syntax (opt-in, see validateStyle)
+	Pipelines string // delimiter comment separating pipelines, "" to treat the input as one
+}
+
+// defaultPipelineDelimiter separates pipelines with { pipelines: true }.
+const defaultPipelineDelimiter = "# --- pipeline ---"
+
+// parseTimings is the per-phase timing breakdown, in milliseconds.
+type parseTimings struct {
+	ParseMs    float64            `json:"parseMs"`
+	ValidateMs float64            `json:"validateMs"`
+	Passes     map[string]float64 `json:"passes,omitempty"`
+}
+
+// readParseOptions reads parseOptions from a JS object such as
+// { timings: true, profile: "ci", allErrors: true, lintIds: true,
+// strict: true, style: true, pipelines: true }, where pipelines may also be
+// the delimiter comment.
+// Anything else yields the defaults.
+func readParseOptions(v js.Value) parseOptions {
+	var opts parseOptions
+	if v.Type() != js.TypeObject {
+		return opts
+	}
+	opts.Timings = v.Get("timings").Truthy()
+	opts.AllErrors = v.Get("allErrors").Truthy()
+	opts.LintIDs = v.Get("lintIds").Truthy()
+	opts.Strict = v.Get("strict").Truthy()
+	opts.Style = v.Get("style").Truthy()
+	if p := v.Get("profile"); p.Type() == js.TypeString {
+		opts.Profile = p.String()
+	}
+	switch p := v.Get("pipelines"); {
+	case p.Type() == js.TypeString && strings.TrimSpace(p.String()) != "":
+		opts.Pipelines = strings.TrimSpace(p.String())
+	case p.Truthy():
+		opts.Pipelines = defaultPipelineDelimiter
+	}
+	return opts
+}
+
+// millisSince returns the time elapsed since start in milliseconds.
+func millisSince(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
 }
 
 var errLineRegex = regexp.MustCompile(`^(?:\S+:)?(\d+):(\d+)\s+\((\d+)\)(?::\s*(?:rule\s+\S+:\s*)?)(.*)`)
 var farthestRegex = regexp.MustCompile(`at pos (\d+):(\d+) \[(\d+)\] and \[(\d+)\]`)
+var farthestOffsetRegex = regexp.MustCompile(`\[(\d+)\]`)
 
 func parseLogstash(this js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
 		return marshal(ParseResult{OK: false, Diagnostics: []Diagnostic{
-			{From: 0, To: 1, Severity: "error", Message: "no input provided"},
+			{From: 0, To: 1, Severity: "error", Message: "no input provided", Code: codeSyntaxError},
 		}})
 	}
 
+	var opts parseOptions
+	if len(args) > 1 {
+		opts = readParseOptions(args[1])
+	}
 	input := args[0].String()
+	// Timings describe a real run, so they bypass the cache.
+	if opts.Timings {
+		return marshal(parseAndValidate(input, opts))
+	}
+	if cached, ok := parseCache.get(input, opts); ok {
+		return cached
+	}
+	result := marshal(parseAndValidate(input, opts))
+	parseCache.put(input, opts, result)
+	return result
+}
+
+// parseCacheSize is the number of parse results kept by parseCache.
+const parseCacheSize = 8
+
+// parseCacheEntry is a marshalled ParseResult for an input and options.
+type parseCacheEntry struct {
+	hash   uint64
+	input  string
+	opts   parseOptions
+	result string
+}
+
+// resultCache is a small LRU of marshalled parse results, so repeated calls
+// with unchanged text (e.g. cursor moves) skip parsing. Entries are ordered
+// most recently used first. JS calls into the WASM one at a time, so it
+// needs no locking.
+type resultCache struct {
+	entries []parseCacheEntry
+}
+
+var parseCache = &resultCache{}
+
+func hashInput(input string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(input))
+	return h.Sum64()
+}
+
+func (c *resultCache) get(input string, opts parseOptions) (string, bool) {
+	hash := hashInput(input)
+	for i, e := range c.entries {
+		if e.hash == hash && e.opts == opts && e.input == input {
+			copy(c.entries[1:i+1], c.entries[:i])
+			c.entries[0] = e
+			return e.result, true
+		}
+	}
+	return "", false
+}
+
+func (c *resultCache) put(input string, opts parseOptions, result string) {
+	entry := parseCacheEntry{hash: hashInput(input), input: input, opts: opts, result: result}
+	if len(c.entries) < parseCacheSize {
+		c.entries = append(c.entries, parseCacheEntry{})
+	}
+	copy(c.entries[1:], c.entries)
+	c.entries[0] = entry
+}
+
+// reset drops all entries; results depend on the active registry.
+func (c *resultCache) reset() {
+	c.entries = nil
+}
+
+// farthest is the deepest position the parser reached before failing, a
+// warning whose message joins Reasons, the parser's complaints there.
+type farthest struct {
+	Diagnostic
+	Reasons []string `json:"reasons"`
+}
+
+// farthestFailureDiagnostic converts a GetFarthestFailure report. The offset
+// is taken from the "at pos line:col [offset]" header, or failing that from
+// its first [offset]; the reasons are its "->" lines. A report with neither
+// gives nil, one without an offset is placed at the start of the input.
+func farthestFailureDiagnostic(input, report string) *farthest {
+	offset := -1
+	if fm := farthestRegex.FindStringSubmatch(report); fm != nil {
+		offset, _ = strconv.Atoi(fm[3])
+	} else if fm := farthestOffsetRegex.FindStringSubmatch(report); fm != nil {
+		offset, _ = strconv.Atoi(fm[1])
+	}
+
+	reasons := []string{}
+	for _, line := range strings.Split(report, "\n") {
+		line = strings.TrimSpace(line)
+		if reason, ok := strings.CutPrefix(line, "->"); ok {
+			if reason = strings.TrimSpace(reason); reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	if offset < 0 && len(reasons) == 0 {
+		return nil
+	}
+
+	msg := strings.Join(reasons, "; ")
+	if msg == "" {
+		msg = "parse failed at this position"
+	}
+	from := min(max(offset, 0), max(0, len(input)-1))
+	to := min(from+1, len(input))
+	return &farthest{
+		Diagnostic: Diagnostic{From: from, To: to, Severity: "warning", Message: msg, Code: codeSyntaxError},
+		Reasons:    reasons,
+	}
+}
+
+// parseAndValidate parses the input and, on success, runs semantic validation.
+// On failure it converts the parser errors into diagnostics. Diagnostics are
+// located by line and column as well as offset.
+func parseAndValidate(input string, opts parseOptions) ParseResult {
+	result := parseAndValidateOffsets(input, opts)
+	ix := newLineIndex(input)
+	ix.locateAll(result.Diagnostics)
+	if result.Farthest != nil {
+		ix.locate(&result.Farthest.Diagnostic)
+	}
+	return result
+}
+
+// parseAndValidateOffsets is parseAndValidate without the line and column
+// fields, which pipelines only get once merged.
+func parseAndValidateOffsets(input string, opts parseOptions) ParseResult {
+	if opts.Pipelines != "" {
+		if starts := pipelineStarts(input, opts.Pipelines); len(starts) > 1 {
+			return parseAndValidatePipelines(input, starts, opts)
+		}
+	}
+
+	var timings *parseTimings
+	if opts.Timings {
+		timings = &parseTimings{Passes: map[string]float64{}}
+	}
+
+	start := time.Now()
 	parsed, err := config.Parse("", []byte(input))
+	if timings != nil {
+		timings.ParseMs = millisSince(start)
+	}
 	if err == nil {
-		result := ParseResult{OK: true, Diagnostics: []Diagnostic{}}
+		result := ParseResult{OK: true, Diagnostics: []Diagnostic{}, Timings: timings}
 		if cfg, ok := parsed.(ast.Config); ok {
-			result.Diagnostics = validate(cfg, input)
+			var passTimes map[string]float64
+			if timings != nil {
+				passTimes = timings.Passes
+			}
+			start = time.Now()
+			unknownSeverity := "warning"
+			if opts.Strict {
+				unknownSeverity = "error"
+			}
+			diags := validateTimed(cfg, input, unknownSeverity, passTimes)
+			profile := lintProfiles[opts.Profile]
+			if opts.LintIDs || profile.enabled[codeMissingID] {
+				diags = validatePluginIDs(cfg, input, diags)
+			}
+			if opts.Style || profile.enabled[codeStyle] {
+				diags = validateStyle(input, diags)
+			}
+			result.Diagnostics = applyLintProfile(opts.Profile, diags)
+			if timings != nil {
+				timings.ValidateMs = millisSince(start)
+			}
+		}
+		return result
+	}
+
+	result := ParseResult{OK: false, Diagnostics: parseErrorDiagnostics(input, err, 0), Timings: timings}
+
+	// Supplementary: farthest failure
+	if ff, ok := config.GetFarthestFailure(); ok {
+		result.Farthest = farthestFailureDiagnostic(input, ff)
+	}
+
+	// Re-parsing sections reports independent errors the first one hides.
+	// It runs after GetFarthestFailure, which reflects the last Parse call.
+	if opts.AllErrors {
+		if diags := sectionSyntaxDiagnostics(input); len(diags) > 0 {
+			result.Diagnostics = diags
+		}
+	}
+
+	// Unbalanced braces are pinned to the brace itself, ahead of the
+	// parser's message at the point it gave up (dropped if at the brace).
+	if braceDiags := braceDiagnostics(input); len(braceDiags) > 0 {
+		atBrace := map[int]bool{}
+		for _, d := range braceDiags {
+			atBrace[d.From] = true
+		}
+		for _, d := range result.Diagnostics {
+			if !atBrace[d.From] {
+				braceDiags = append(braceDiags, d)
+			}
+		}
+		result.Diagnostics = braceDiags
+	}
+
+	if len(result.Diagnostics) == 0 {
+		result.Diagnostics = append(result.Diagnostics, Diagnostic{
+			From: 0, To: min(1, len(input)), Severity: "error", Message: err.Error(), Code: codeSyntaxError,
+		})
+	}
+
+	return result
+}
+
+// pipelineStarts returns the offsets where pipelines begin: 0 and the start
+// of every line consisting of the delimiter comment.
+func pipelineStarts(input, delimiter string) []int {
+	starts := []int{0}
+	for lineStart := 0; lineStart < len(input); {
+		lineEnd := strings.IndexByte(input[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(input)
+		} else {
+			lineEnd += lineStart
+		}
+		if lineStart > 0 && strings.TrimSpace(input[lineStart:lineEnd]) == delimiter {
+			starts = append(starts, lineStart)
 		}
-		return marshal(result)
+		lineStart = lineEnd + 1
+	}
+	return starts
+}
+
+// parseAndValidatePipelines parses and validates each pipeline on its own,
+// so that e.g. two pipelines' filter sections aren't reported as duplicates,
+// and merges the results with offsets into input.
+func parseAndValidatePipelines(input string, starts []int, opts parseOptions) ParseResult {
+	opts.Pipelines = ""
+	result := ParseResult{OK: true, Diagnostics: []Diagnostic{}, MultiPipeline: true}
+	if opts.Timings {
+		result.Timings = &parseTimings{Passes: map[string]float64{}}
 	}
 
-	result := ParseResult{OK: false, Diagnostics: []Diagnostic{}}
+	for i, start := range starts {
+		end := len(input)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		part := parseAndValidateOffsets(input[start:end], opts)
+		result.OK = result.OK && part.OK
+		for _, d := range part.Diagnostics {
+			d = shiftDiagnostic(d, start)
+			if d.Code == codeSyntaxError {
+				d.Message = documentPositions(input, d.Message, start)
+			}
+			result.Diagnostics = append(result.Diagnostics, d)
+		}
+		if part.Farthest != nil && result.Farthest == nil {
+			f := *part.Farthest
+			f.Diagnostic = shiftDiagnostic(f.Diagnostic, start)
+			result.Farthest = &f
+		}
+		if t := part.Timings; t != nil {
+			result.Timings.ParseMs += t.ParseMs
+			result.Timings.ValidateMs += t.ValidateMs
+			for name, ms := range t.Passes {
+				result.Timings.Passes[name] += ms
+			}
+		}
+	}
+	return result
+}
+
+// parseErrorDiagnostics converts a parser error into diagnostics, one per
+// distinct offset. base is added to the error offsets, for errors from
+// parsing the part of input starting at base; the positions quoted in the
+// messages are translated likewise. The parser's "->" reason lines are
+// folded into the message of the error they follow.
+func parseErrorDiagnostics(input string, err error, base int) []Diagnostic {
+	diags := []Diagnostic{}
 	seen := map[int]bool{}
+	last := -1 // index of the diagnostic reasons are folded into, -1 for none
 
 	for _, line := range strings.Split(err.Error(), "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
+		if reason, ok := strings.CutPrefix(line, "->"); ok {
+			if reason = strings.TrimSpace(reason); reason != "" && last >= 0 {
+				diags[last].Message = withReason(diags[last].Message, reason)
+			}
+			continue
+		}
 		m := errLineRegex.FindStringSubmatch(line)
 		if m == nil {
+			last = -1
 			if !seen[-1] {
 				seen[-1] = true
-				result.Diagnostics = append(result.Diagnostics, Diagnostic{
-					From: 0, To: min(1, len(input)), Severity: "error", Message: line,
+				from := min(base, max(0, len(input)-1))
+				diags = append(diags, Diagnostic{
+					From: from, To: min(from+1, len(input)), Severity: "error", Message: line, Code: codeSyntaxError,
 				})
+				last = len(diags) - 1
 			}
 			continue
 		}
 		offset, _ := strconv.Atoi(m[3])
-		msg := m[4]
+		offset += base
+		msg := documentPositions(input, m[4], base)
 		if msg == "" {
 			msg = line
 		}
+		last = -1
 		if !seen[offset] {
 			seen[offset] = true
 			from := min(offset, max(0, len(input)-1))
 			to := min(from+1, len(input))
-			result.Diagnostics = append(result.Diagnostics, Diagnostic{
-				From: from, To: to, Severity: "error", Message: msg,
-			})
+			d := Diagnostic{From: from, To: to, Severity: "error", Message: msg, Code: codeSyntaxError}
+			if name := bareConditionOperand(input, from); name != "" {
+				ref := "[" + name + "]"
+				d.To = from + len(name)
+				d.Message = "expected a field reference like " + ref
+				d.Fix = &Fix{Label: "Use " + ref, From: from, To: d.To, Insert: ref}
+			} else {
+				last = len(diags)
+			}
+			diags = append(diags, d)
 		}
 	}
+	return diags
+}
 
-	// Supplementary: farthest failure
-	if ff, ok := config.GetFarthestFailure(); ok {
-		if fm := farthestRegex.FindStringSubmatch(ff); fm != nil {
-			offset, _ := strconv.Atoi(fm[3])
-			var msgs []string
-			for _, fl := range strings.Split(ff, "\n") {
-				fl = strings.TrimSpace(fl)
-				if strings.HasPrefix(fl, "->") {
-					msgs = append(msgs, strings.TrimSpace(strings.TrimPrefix(fl, "->")))
-				}
-			}
-			msg := strings.Join(msgs, "; ")
-			if msg == "" {
-				msg = "parse failed at this position"
-			}
-			from := min(offset, max(0, len(input)-1))
-			to := min(from+1, len(input))
-			result.Farthest = &Diagnostic{
-				From: from, To: to, Severity: "warning", Message: msg,
-			}
+// withReason appends a parser reason to an error message, e.g. "Parsing
+// error at pos 3:27 [55] and [56] (after: ' '): expect closing square
+// bracket".
+func withReason(msg, reason string) string {
+	if strings.HasSuffix(msg, ":") {
+		return msg + " " + reason
+	}
+	return msg + "; " + reason
+}
+
+// documentPositions rewrites the "at pos line:col [offset] and [offset]" of
+// a message from parsing the part of input starting at base into positions
+// in the whole input.
+func documentPositions(input, msg string, base int) string {
+	if base == 0 {
+		return msg
+	}
+	baseLine, baseCol := newLineIndex(input).position(base)
+	return farthestRegex.ReplaceAllStringFunc(msg, func(pos string) string {
+		m := farthestRegex.FindStringSubmatch(pos)
+		line, _ := strconv.Atoi(m[1])
+		col, _ := strconv.Atoi(m[2])
+		from, _ := strconv.Atoi(m[3])
+		to, _ := strconv.Atoi(m[4])
+		if line == 1 {
+			col += baseCol - 1
+		}
+		return fmt.Sprintf("at pos %d:%d [%d] and [%d]", line+baseLine-1, col, from+base, to+base)
+	})
+}
+
+// diagnosticsSummary aggregates diagnostics for status-bar style displays.
+type diagnosticsSummary struct {
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Infos    int            `json:"infos"`
+	ByCode   map[string]int `json:"byCode"`
+}
+
+func summarizeDiagnostics(diags []Diagnostic) diagnosticsSummary {
+	summary := diagnosticsSummary{ByCode: map[string]int{}}
+	for _, d := range diags {
+		switch d.Severity {
+		case "error":
+			summary.Errors++
+		case "warning":
+			summary.Warnings++
+		case "info":
+			summary.Infos++
+		}
+		if d.Code != "" {
+			summary.ByCode[d.Code]++
 		}
 	}
+	return summary
+}
 
-	if len(result.Diagnostics) == 0 {
-		result.Diagnostics = append(result.Diagnostics, Diagnostic{
-			From: 0, To: min(1, len(input)), Severity: "error", Message: err.Error(),
-		})
+// sentence renders the counts by severity for a status line, e.g.
+// "1 error, 2 warnings, 1 note", or "valid" when there are none.
+func (s diagnosticsSummary) sentence() string {
+	var parts []string
+	for _, c := range []struct {
+		n                int
+		singular, plural string
+	}{
+		{s.Errors, "error", "errors"},
+		{s.Warnings, "warning", "warnings"},
+		{s.Infos, "note", "notes"},
+	} {
+		switch {
+		case c.n == 1:
+			parts = append(parts, "1 "+c.singular)
+		case c.n > 1:
+			parts = append(parts, strconv.Itoa(c.n)+" "+c.plural)
+		}
 	}
+	if len(parts) == 0 {
+		return "valid"
+	}
+	return strings.Join(parts, ", ")
+}
 
-	return marshal(result)
+// getDiagnosticsSummary is the WASM entry point returning diagnostic counts
+// by severity and code instead of the full diagnostics list.
+func getDiagnosticsSummary(this js.Value, args []js.Value) interface{} {
+	var diags []Diagnostic
+	if len(args) > 0 {
+		diags = parseAndValidate(args[0].String(), parseOptions{}).Diagnostics
+	}
+	b, _ := json.Marshal(summarizeDiagnostics(diags))
+	return string(b)
 }
 
+// marshal serializes a parse result, stamped with the active registry
+// version and its plugin count, and summarizes its diagnostics.
 func marshal(r ParseResult) string {
+	r.Summary = summarizeDiagnostics(r.Diagnostics).sentence()
+	mu.RLock()
+	r.Version = currentVersion
+	for _, plugins := range knownPlugins {
+		r.KnownPlugins += len(plugins)
+	}
+	mu.RUnlock()
 	b, _ := json.Marshal(r)
 	return string(b)
 }
@@ -128,6 +634,40 @@ func setLogstashVersion(this js.Value, args []js.Value) interface{} {
 	return string(b)
 }
 
+// loadRegistryFromJSON registers registry data fetched at runtime so it can
+// be selected with setLogstashVersion without rebuilding the WASM.
+// Args: version, registry JSON (as produced by tools/scrape-registry),
+// activate (optional; true also makes it the active registry). Malformed
+// JSON is rejected and leaves the active registry as it was.
+func loadRegistryFromJSON(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": "version and registry JSON required"})
+		return string(b)
+	}
+	version := args[0].String()
+	err := registerVersion(version, []byte(args[1].String()))
+	if err == nil && len(args) > 2 && args[2].Truthy() {
+		err = loadVersion(version)
+	}
+	if err != nil {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": err.Error()})
+		return string(b)
+	}
+	b, _ := json.Marshal(map[string]interface{}{"ok": true})
+	return string(b)
+}
+
+// listLogstashVersions returns the selectable registry versions as a JSON
+// array, e.g. ["8.15","8.17","8.19"].
+func listLogstashVersions(this js.Value, args []js.Value) interface{} {
+	versions := availableVersions()
+	if versions == nil {
+		versions = []string{}
+	}
+	b, _ := json.Marshal(versions)
+	return string(b)
+}
+
 func getLogstashVersions(this js.Value, args []js.Value) interface{} {
 	mu.RLock()
 	cur := currentVersion
@@ -144,7 +684,25 @@ func main() {
 	js.Global().Set("parseLogstashConfig", js.FuncOf(parseLogstash))
 	js.Global().Set("setLogstashVersion", js.FuncOf(setLogstashVersion))
 	js.Global().Set("getLogstashVersions", js.FuncOf(getLogstashVersions))
+	js.Global().Set("listLogstashVersions", js.FuncOf(listLogstashVersions))
+	js.Global().Set("diffLogstashVersions", js.FuncOf(diffLogstashVersions))
+	js.Global().Set("loadLogstashRegistry", js.FuncOf(loadRegistryFromJSON))
 	js.Global().Set("getLogstashCompletions", js.FuncOf(getCompletions))
 	js.Global().Set("getLogstashContextInfo", js.FuncOf(getContextInfo))
+	js.Global().Set("getLogstashPluginDoc", js.FuncOf(getPluginDoc))
+	js.Global().Set("getLogstashAvailableCodecs", js.FuncOf(getAvailableCodecs))
+	js.Global().Set("getLogstashDocUrl", js.FuncOf(getDocURL))
+	js.Global().Set("getLogstashDiagnosticsSummary", js.FuncOf(getDiagnosticsSummary))
+	js.Global().Set("explainLogstashDiagnostic", js.FuncOf(getExplanation))
+	js.Global().Set("searchLogstashRegistry", js.FuncOf(getSearchResults))
+	js.Global().Set("dumpLogstashAST", js.FuncOf(getASTDump))
+	js.Global().Set("getLogstashInsertPosition", js.FuncOf(getInsertPosition))
+	js.Global().Set("formatLogstashConfig", js.FuncOf(formatLogstashConfig))
+	js.Global().Set("getLogstashFoldRanges", js.FuncOf(getFoldRanges))
+	js.Global().Set("getLogstashSemanticTokens", js.FuncOf(getSemanticTokens))
+	js.Global().Set("validateLogstashConfig", js.FuncOf(validateLogstashConfig))
+	js.Global().Set("validateLogstashStreamBegin", js.FuncOf(validateStreamBegin))
+	js.Global().Set("validateLogstashStreamChunk", js.FuncOf(validateStreamChunk))
+	js.Global().Set("validateLogstashStreamEnd", js.FuncOf(validateStreamEnd))
 	select {}
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	initRegistry()
+	os.Exit(m.Run())
+}
+
+// diagnosticsFor parses and validates src with opts, failing the test when
+// it doesn't parse.
+func diagnosticsFor(t *testing.T, src string, opts parseOptions) []Diagnostic {
+	t.Helper()
+	result := parseAndValidate(src, opts)
+	if !result.OK {
+		t.Fatalf("%q doesn't parse: %+v", src, result.Diagnostics)
+	}
+	return result.Diagnostics
+}
+
+// withCode returns the diagnostics with the given code.
+func withCode(diags []Diagnostic, code string) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range diags {
+		if d.Code == code {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// useRegistry registers data as a registry version and activates it for
+// the rest of the test, restoring the previous version afterwards.
+func useRegistry(t *testing.T, data string) {
+	t.Helper()
+	mu.RLock()
+	prev := currentVersion
+	mu.RUnlock()
+	version := "test-" + t.Name()
+	if err := registerVersion(version, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadVersion(version); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := loadVersion(prev); err != nil {
+			t.Error(err)
+		}
+	})
+}
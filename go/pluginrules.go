@@ -0,0 +1,525 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/breml/logstash-config/ast"
+)
+
+// pluginRule checks configuration mistakes specific to a single plugin.
+type pluginRule func(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic
+
+// pluginRules maps a type-qualified plugin key (e.g. "filter/sleep") to its
+// plugin-specific checks. Rules only run for plugins known to the registry.
+var pluginRules = map[string]pluginRule{
+	"input/dead_letter_queue": validateDeadLetterQueue,
+	"input/elasticsearch":     validateElasticsearchSSL,
+	"input/http_poller":       validateHTTPPoller,
+
+	"filter/elasticsearch": validateElasticsearchSSL,
+	"filter/grok":          validateGrok,
+	"filter/kv":            validateKv,
+	"filter/mutate":        validateMutate,
+	"filter/ruby":          validateRuby,
+	"filter/sleep":         validateSleep,
+
+	"output/elasticsearch": validateElasticsearchSSL,
+}
+
+// greedyDataRegex matches a %{GREEDYDATA} capture.
+var greedyDataRegex = regexp.MustCompile(`%\{GREEDYDATA(?::[^}]*)?\}`)
+
+// nestedQuantifierRegex matches a group containing an unescaped * or + that
+// is itself repeated, e.g. (\w+\s?)+ or (.*)*.
+var nestedQuantifierRegex = regexp.MustCompile(`\((?:[^()\\]|\\.)*?[*+](?:[^()\\]|\\.)*\)[*+{]`)
+
+// legacyEventAccessRegex matches the pre-5.0 event["field"] accessor style.
+var legacyEventAccessRegex = regexp.MustCompile(`\bevent\s*\[`)
+
+// findAttribute returns the plugin attribute with the given name, or nil.
+func findAttribute(plugin ast.Plugin, name string) ast.Attribute {
+	for _, attr := range plugin.Attributes {
+		if attr != nil && optionName(attr) == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+// pluginDiagnostic builds a diagnostic highlighting the plugin name.
+func pluginDiagnostic(plugin ast.Plugin, input, severity, code, message string) Diagnostic {
+	from := clampFrom(plugin.Pos().Offset, input)
+	to := clampTo(from+len(plugin.Name()), input)
+	return Diagnostic{From: from, To: to, Severity: severity, Message: message, Code: code}
+}
+
+// stringAttributes returns all string values within an attribute, including
+// those nested in arrays and hashes.
+func stringAttributes(attr ast.Attribute) []ast.StringAttribute {
+	switch v := attr.(type) {
+	case ast.StringAttribute:
+		return []ast.StringAttribute{v}
+	case ast.ArrayAttribute:
+		var out []ast.StringAttribute
+		for _, a := range v.Attributes {
+			out = append(out, stringAttributes(a)...)
+		}
+		return out
+	case ast.HashAttribute:
+		var out []ast.StringAttribute
+		for _, entry := range v.Entries {
+			out = append(out, stringAttributes(entry.Value)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// literalContentOffset returns the offset of the first content character
+// of a string nested in an array or hash (whose Start is the opening quote).
+func literalContentOffset(sa ast.StringAttribute) int {
+	if sa.StringAttributeType() == ast.Bareword {
+		return sa.Pos().Offset
+	}
+	return sa.Pos().Offset + 1
+}
+
+// isSprintf reports whether a string value contains a %{...} reference,
+// in which case its runtime value can't be checked statically.
+func isSprintf(s string) bool {
+	return strings.Contains(s, "%{")
+}
+
+// validateSleep checks that sleep's "time" is numeric and "every" is a
+// positive integer.
+func validateSleep(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	if attr := findAttribute(plugin, "time"); attr != nil {
+		valid := false
+		switch v := attr.(type) {
+		case ast.NumberAttribute:
+			valid = true
+		case ast.StringAttribute:
+			_, err := strconv.ParseFloat(v.Value(), 64)
+			valid = err == nil || isSprintf(v.Value())
+		}
+		if !valid {
+			diags = append(diags, valueDiagnostic(attr, input, "warning", codeInvalidValue,
+				fmt.Sprintf("sleep \"time\" must be a number of seconds, got %s", describeValue(attr))))
+		}
+	}
+
+	if attr := findAttribute(plugin, "every"); attr != nil {
+		valid := false
+		switch v := attr.(type) {
+		case ast.NumberAttribute:
+			valid = v.Value() > 0 && v.Value() == float64(int64(v.Value()))
+		case ast.StringAttribute:
+			n, err := strconv.ParseInt(v.Value(), 10, 64)
+			valid = (err == nil && n > 0) || isSprintf(v.Value())
+		}
+		if !valid {
+			diags = append(diags, valueDiagnostic(attr, input, "warning", codeInvalidValue,
+				fmt.Sprintf("sleep \"every\" must be a positive integer, got %s", describeValue(attr))))
+		}
+	}
+
+	return diags
+}
+
+// validateRuby flags inline code using the event[...] accessor, which was
+// removed in Logstash 5.0 in favour of event.get/event.set.
+func validateRuby(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	sa, ok := findAttribute(plugin, "code").(ast.StringAttribute)
+	if !ok {
+		return diags
+	}
+
+	base := stringContentOffset(sa, input)
+	for _, loc := range legacyEventAccessRegex.FindAllStringIndex(sa.Value(), -1) {
+		from := clampFrom(base+loc[0], input)
+		to := clampTo(base+loc[1], input)
+		diags = append(diags, Diagnostic{
+			From:     from,
+			To:       to,
+			Severity: "info",
+			Message:  "event[...] access was removed in Logstash 5.0; use event.get(...) and event.set(...)",
+			Code:     codeRubyEventAPI,
+		})
+	}
+	return diags
+}
+
+// validateKv flags include_keys combined with exclude_keys (ambiguous) and
+// empty trim settings (no-ops). trim/trimkey are the pre-4.0 names of
+// trim_value/trim_key.
+func validateKv(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	include := findAttribute(plugin, "include_keys")
+	exclude := findAttribute(plugin, "exclude_keys")
+	if include != nil && exclude != nil && !isEmptyValue(include) && !isEmptyValue(exclude) {
+		second, first := exclude, include
+		if include.Pos().Offset > exclude.Pos().Offset {
+			second, first = include, exclude
+		}
+		from := clampFrom(second.Pos().Offset, input)
+		to := clampTo(from+len(second.Name()), input)
+		diags = append(diags, Diagnostic{
+			From:     from,
+			To:       to,
+			Severity: "warning",
+			Message:  fmt.Sprintf("%q and %q are both set; use only one to select keys", optionName(first), optionName(second)),
+			Code:     codeConflictingOpts,
+		})
+	}
+
+	for _, name := range []string{"trim", "trimkey", "trim_value", "trim_key"} {
+		if attr := findAttribute(plugin, name); attr != nil && isEmptyValue(attr) {
+			diags = append(diags, valueDiagnostic(attr, input, "warning", codeNoopOption,
+				fmt.Sprintf("empty %q has no effect", name)))
+		}
+	}
+
+	return diags
+}
+
+// isEmptyValue reports whether a value is an empty string, array or hash.
+func isEmptyValue(attr ast.Attribute) bool {
+	switch v := attr.(type) {
+	case ast.StringAttribute:
+		return v.Value() == ""
+	case ast.ArrayAttribute:
+		return len(v.Attributes) == 0
+	case ast.HashAttribute:
+		return len(v.Entries) == 0
+	}
+	return false
+}
+
+// validateDeadLetterQueue checks that the dead_letter_queue input's "path"
+// (the path.dead_letter_queue directory of the writing Logstash) isn't empty.
+// A missing path is reported by validateRequiredOptions.
+func validateDeadLetterQueue(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	attr := findAttribute(plugin, "path")
+	if attr != nil && isEmptyValue(attr) {
+		diags = append(diags, valueDiagnostic(attr, input, "warning", codeInvalidValue,
+			`dead_letter_queue "path" must not be empty`))
+	}
+	return diags
+}
+
+// httpPollerScheduleKeys are the rufus-scheduler modes accepted by
+// http_poller's "schedule" hash.
+var httpPollerScheduleKeys = map[string]bool{"cron": true, "every": true, "in": true, "at": true}
+
+// validateHTTPPoller checks that "urls" is a non-empty hash and that
+// "schedule" is a valid schedule hash; without one the poller never runs.
+// Missing options are reported by validateRequiredOptions.
+func validateHTTPPoller(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	if urls := findAttribute(plugin, "urls"); urls != nil {
+		if hash, ok := urls.(ast.HashAttribute); !ok {
+			diags = append(diags, valueDiagnostic(urls, input, "warning", codeInvalidValue,
+				fmt.Sprintf("http_poller \"urls\" must be a hash of name => url or request, got %s", describeValue(urls))))
+		} else if len(hash.Entries) == 0 {
+			diags = append(diags, valueDiagnostic(urls, input, "warning", codeInvalidValue,
+				`http_poller "urls" is empty; nothing will be polled`))
+		}
+	}
+
+	schedule := findAttribute(plugin, "schedule")
+	if schedule == nil {
+		return diags
+	}
+	hash, ok := schedule.(ast.HashAttribute)
+	if !ok {
+		return append(diags, valueDiagnostic(schedule, input, "warning", codeInvalidValue,
+			fmt.Sprintf("http_poller \"schedule\" must be a hash such as { \"every\" => \"1m\" }, got %s", describeValue(schedule))))
+	}
+	if len(hash.Entries) == 0 {
+		return append(diags, valueDiagnostic(schedule, input, "warning", codeInvalidValue,
+			`http_poller "schedule" is empty; use one of "cron", "every", "in" or "at"`))
+	}
+
+	for _, entry := range hash.Entries {
+		key := hashKeyName(entry)
+		if !httpPollerScheduleKeys[key] {
+			from := clampFrom(entry.Key.Pos().Offset, input)
+			to := clampTo(from+len(entry.Name()), input)
+			diags = append(diags, Diagnostic{
+				From:     from,
+				To:       to,
+				Severity: "warning",
+				Message:  fmt.Sprintf("unknown schedule %q; use one of \"cron\", \"every\", \"in\" or \"at\"", key),
+				Code:     codeInvalidValue,
+			})
+			continue
+		}
+		if key != "cron" {
+			continue
+		}
+		sa, ok := entry.Value.(ast.StringAttribute)
+		if !ok {
+			continue
+		}
+		// Five fields, or six with seconds, optionally followed by a time zone.
+		if n := len(strings.Fields(sa.Value())); n < 5 || n > 7 {
+			from, to := entryValueRange(entry, input)
+			diags = append(diags, Diagnostic{
+				From:     from,
+				To:       to,
+				Severity: "info",
+				Message:  fmt.Sprintf("cron expression has %d fields; expected 5 (or 6 with seconds) plus an optional time zone", n),
+				Code:     codeInvalidValue,
+			})
+		}
+	}
+	return diags
+}
+
+// elasticsearchSSLAliases maps the deprecated SSL options of the
+// elasticsearch input, filter and output to their ssl_* replacements.
+// Logstash refuses to start when both names are set.
+var elasticsearchSSLAliases = map[string]string{
+	"ssl":                          "ssl_enabled",
+	"cacert":                       "ssl_certificate_authorities",
+	"ca_file":                      "ssl_certificate_authorities",
+	"ssl_certificate_verification": "ssl_verification_mode",
+	"keystore":                     "ssl_keystore_path",
+	"keystore_password":            "ssl_keystore_password",
+	"truststore":                   "ssl_truststore_path",
+	"truststore_password":          "ssl_truststore_password",
+}
+
+// elasticsearchTrustOptions are the options that supply a CA to verify the
+// cluster certificate against.
+var elasticsearchTrustOptions = []string{
+	"ssl_certificate_authorities", "ssl_truststore_path", "cacert", "ca_file", "truststore",
+}
+
+// validateElasticsearchSSL flags deprecated SSL options set alongside their
+// replacement, and SSL enabled with verification but no CA configured.
+func validateElasticsearchSSL(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	for _, attr := range plugin.Attributes {
+		if attr == nil {
+			continue
+		}
+		old := optionName(attr)
+		replacement, ok := elasticsearchSSLAliases[old]
+		if !ok || findAttribute(plugin, replacement) == nil {
+			continue
+		}
+		from := clampFrom(attr.Pos().Offset, input)
+		to := clampTo(from+len(attr.Name()), input)
+		diags = append(diags, Diagnostic{
+			From:     from,
+			To:       to,
+			Severity: "error",
+			Message:  fmt.Sprintf("%q is the deprecated name of %q and both are set; Logstash won't start, keep only %q", old, replacement, replacement),
+			Code:     codeConflictingOpts,
+		})
+	}
+
+	enabled := findAttribute(plugin, "ssl_enabled")
+	if enabled == nil {
+		enabled = findAttribute(plugin, "ssl")
+	}
+	if enabled == nil || unquote(enabled.ValueString()) != "true" {
+		return diags
+	}
+	if findAttribute(plugin, "cloud_id") != nil {
+		return diags // Elastic Cloud certificates are publicly trusted
+	}
+	if mode := findAttribute(plugin, "ssl_verification_mode"); mode != nil && unquote(mode.ValueString()) == "none" {
+		return diags
+	}
+	if verify := findAttribute(plugin, "ssl_certificate_verification"); verify != nil && unquote(verify.ValueString()) == "false" {
+		return diags
+	}
+	for _, name := range elasticsearchTrustOptions {
+		if findAttribute(plugin, name) != nil {
+			return diags
+		}
+	}
+	return append(diags, valueDiagnostic(enabled, input, "warning", codeMissingOption,
+		fmt.Sprintf("%q is enabled but no CA is configured; set \"ssl_certificate_authorities\" or \"ssl_truststore_path\" unless the cluster certificate is publicly trusted", optionName(enabled))))
+}
+
+// unquote strips surrounding quotes from a scalar ValueString.
+func unquote(s string) string {
+	return strings.Trim(s, `"'`)
+}
+
+// validateGrok flags match patterns prone to catastrophic backtracking:
+// GREEDYDATA followed by further captures, and nested repeated quantifiers.
+// This is heuristic, so it is info only.
+func validateGrok(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	match := findAttribute(plugin, "match")
+	if match == nil {
+		return diags
+	}
+
+	// Names defined by the config itself are never typos. With patterns_dir
+	// any name may be defined elsewhere, so pattern names aren't checked.
+	checkNames := findAttribute(plugin, "patterns_dir") == nil
+	defined := map[string]bool{}
+	if defs, ok := findAttribute(plugin, "pattern_definitions").(ast.HashAttribute); ok {
+		for _, entry := range defs.Entries {
+			defined[hashKeyName(entry)] = true
+		}
+	}
+
+	for _, sa := range stringAttributes(match) {
+		pattern := sa.Value()
+		base := literalContentOffset(sa)
+		if _, ok := match.(ast.StringAttribute); ok {
+			base = stringContentOffset(sa, input)
+		}
+
+		for _, loc := range greedyDataRegex.FindAllStringIndex(pattern, -1) {
+			if !strings.Contains(pattern[loc[1]:], "%{") {
+				continue
+			}
+			diags = append(diags, Diagnostic{
+				From:     clampFrom(base+loc[0], input),
+				To:       clampTo(base+loc[1], input),
+				Severity: "info",
+				Message:  "GREEDYDATA followed by more patterns can backtrack heavily on lines that don't match; prefer DATA or a more specific pattern and anchor with ^...$",
+				Code:     codeGrokBacktracking,
+			})
+		}
+		for _, loc := range nestedQuantifierRegex.FindAllStringIndex(pattern, -1) {
+			diags = append(diags, Diagnostic{
+				From:     clampFrom(base+loc[0], input),
+				To:       clampTo(base+loc[1], input),
+				Severity: "info",
+				Message:  "nested repeated quantifiers can cause catastrophic backtracking; make the inner or outer repetition unambiguous",
+				Code:     codeGrokBacktracking,
+			})
+		}
+		if !checkNames {
+			continue
+		}
+		for _, loc := range grokReferenceRegex.FindAllStringSubmatchIndex(pattern, -1) {
+			name := pattern[loc[2]:loc[3]]
+			suggestion := grokPatternTypo(name)
+			if suggestion == "" || defined[name] {
+				continue
+			}
+			from := clampFrom(base+loc[2], input)
+			to := clampTo(base+loc[3], input)
+			diags = append(diags, Diagnostic{
+				From:     from,
+				To:       to,
+				Severity: "warning",
+				Message:  fmt.Sprintf("unknown grok pattern %q, did you mean %q?", name, suggestion),
+				Code:     codeUnknownPattern,
+				Fix:      &Fix{Label: "Use " + suggestion, From: from, To: to, Insert: suggestion},
+			})
+		}
+	}
+	return diags
+}
+
+// grokReferenceRegex matches a %{NAME} or %{NAME:field...} reference to an
+// all-caps pattern name (group 1).
+var grokReferenceRegex = regexp.MustCompile(`%\{([A-Z0-9_]+)(?::[^}]*)?\}`)
+
+// grokPatternsNotListed are builtin patterns left out of grokPatterns that
+// are one edit away from a listed one.
+var grokPatternsNotListed = map[string]bool{"MONTHNUM2": true, "URN": true}
+
+// grokPatternTypo returns the builtin pattern name is likely a typo of, or
+// "". To stay clear of user-defined patterns, only names of 4+ characters
+// one edit (or swap) away from a name in grokPatterns are reported.
+func grokPatternTypo(name string) string {
+	if len(name) < 4 || grokPatternsNotListed[name] || slices.Contains(grokPatterns, name) {
+		return ""
+	}
+	for _, known := range grokPatterns {
+		if editDistance(name, known) == 1 {
+			return known
+		}
+	}
+	return ""
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and swaps of adjacent characters cost 1.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// regexSyntaxErrors are the regexp/syntax errors that are also errors in
+// Ruby's regex engine. Other failures (lookarounds, possessive quantifiers,
+// ...) are RE2 limitations, not mistakes.
+var regexSyntaxErrors = map[syntax.ErrorCode]bool{
+	syntax.ErrMissingBracket:        true,
+	syntax.ErrMissingParen:          true,
+	syntax.ErrUnexpectedParen:       true,
+	syntax.ErrMissingRepeatArgument: true,
+	syntax.ErrTrailingBackslash:     true,
+	syntax.ErrInvalidCharRange:      true,
+}
+
+// validateMutate checks that gsub is a flat array of field, pattern,
+// replacement triples and that each pattern is a valid regex.
+func validateMutate(plugin ast.Plugin, input string, diags []Diagnostic) []Diagnostic {
+	attr := findAttribute(plugin, "gsub")
+	if attr == nil {
+		return diags
+	}
+	arr, ok := attr.(ast.ArrayAttribute)
+	if !ok {
+		return append(diags, valueDiagnostic(attr, input, "warning", codeInvalidValue,
+			fmt.Sprintf("mutate \"gsub\" must be an array of field, pattern, replacement triples, got %s", describeValue(attr))))
+	}
+	if n := len(arr.Attributes); n%3 != 0 {
+		diags = append(diags, valueDiagnostic(attr, input, "warning", codeInvalidValue,
+			fmt.Sprintf("mutate \"gsub\" has %d elements; it takes field, pattern, replacement triples", n)))
+	}
+
+	for i := 1; i < len(arr.Attributes); i += 3 {
+		sa, ok := arr.Attributes[i].(ast.StringAttribute)
+		if !ok {
+			continue
+		}
+		_, err := syntax.Parse(sa.Value(), syntax.Perl)
+		var serr *syntax.Error
+		if !errors.As(err, &serr) || !regexSyntaxErrors[serr.Code] {
+			continue
+		}
+		from := clampFrom(sa.Pos().Offset, input)
+		diags = append(diags, Diagnostic{
+			From:     from,
+			To:       clampTo(from+len(sa.ValueString()), input),
+			Severity: "warning",
+			Message:  fmt.Sprintf("invalid gsub pattern: %s", serr.Code),
+			Code:     codeInvalidValue,
+		})
+	}
+	return diags
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"grok", "grok", 0},
+		{"grk", "grok", 1},
+		{"gork", "grok", 1}, // a transposition is one edit
+		{"gokr", "grok", 2},
+		{"", "abc", 3},
+		{"mutate", "mutant", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
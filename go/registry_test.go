@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkSensitive(t *testing.T) {
+	tests := []struct {
+		plugin, option string
+		want           bool
+	}{
+		{"output/elasticsearch", "password", true},
+		{"output/elasticsearch", "hosts", true}, // list of uri
+		{"output/elasticsearch", "proxy", true}, // uri
+		{"input/elasticsearch", "proxy", true},  // uri_or_empty
+		{"output/elasticsearch", "user", false},
+		{"output/elasticsearch", "index", false},
+	}
+	for _, tt := range tests {
+		pluginType, pluginName, _ := strings.Cut(tt.plugin, "/")
+		doc := getOptionDocInfo(pluginType, pluginName, tt.option)
+		if doc == nil {
+			t.Fatalf("%s %s: no option doc", tt.plugin, tt.option)
+		}
+		if doc.Sensitive != tt.want {
+			t.Errorf("%s %s (%s): Sensitive = %v, want %v", tt.plugin, tt.option, doc.Type, doc.Sensitive, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"syscall/js"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchResults = 50  // matches searchRegistry returns at most
+	maxSnippetLength = 160 // bytes of description kept per match
+)
+
+// searchResult is one registry entry matching a search. Kind is "plugin",
+// "codec" or "option". For options, SectionType and PluginName name the
+// plugin (SectionType "codec" for codec options); common options have an
+// empty PluginName. Description is a snippet of the entry's doc.
+type searchResult struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	SectionType string `json:"sectionType,omitempty"`
+	PluginName  string `json:"pluginName,omitempty"`
+	Description string `json:"description,omitempty"`
+	Score       int    `json:"score"`
+}
+
+// searchRegistry fuzzy-matches a query against every plugin, codec and
+// option name of the active registry, scored by fuzzyScore. It returns at
+// most maxSearchResults matches, best first, with plugins before codecs
+// before options among equal scores.
+func searchRegistry(query string) []searchResult {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	// Collect the names under the lock; the doc lookups below take it too.
+	type entry struct{ kind, name, sectionType, pluginName string }
+	var entries []entry
+	mu.RLock()
+	for _, pt := range []string{"input", "filter", "output"} {
+		for name := range knownPlugins[pluginTypeMap[pt]] {
+			entries = append(entries, entry{"plugin", name, pt, ""})
+		}
+	}
+	for name := range knownCodecs {
+		entries = append(entries, entry{"codec", name, "", ""})
+	}
+	for _, pt := range []string{"input", "filter", "output"} {
+		for name := range commonOptions[pluginTypeMap[pt]] {
+			entries = append(entries, entry{"option", name, pt, ""})
+		}
+	}
+	for key, opts := range pluginOptions {
+		sectionType, pluginName, _ := strings.Cut(key, "/")
+		for name := range opts {
+			entries = append(entries, entry{"option", name, sectionType, pluginName})
+		}
+	}
+	mu.RUnlock()
+
+	var results []searchResult
+	for _, e := range entries {
+		score := fuzzyScore(query, e.name)
+		if score == 0 {
+			continue
+		}
+		results = append(results, searchResult{
+			Kind:        e.kind,
+			Name:        e.name,
+			SectionType: e.sectionType,
+			PluginName:  e.pluginName,
+			Score:       score,
+		})
+	}
+
+	kindOrder := map[string]int{"plugin": 0, "codec": 1, "option": 2}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.SectionType != b.SectionType {
+			return a.SectionType < b.SectionType
+		}
+		return a.PluginName < b.PluginName
+	})
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+	for i, r := range results {
+		results[i].Description = descriptionSnippet(searchDescription(r.Kind, r.Name, r.SectionType, r.PluginName))
+	}
+	return results
+}
+
+// searchDescription returns the registry description of a search entry.
+func searchDescription(kind, name, sectionType, pluginName string) string {
+	switch kind {
+	case "plugin":
+		if doc := getPluginDocInfo(sectionType, name); doc != nil {
+			return doc.Description
+		}
+	case "codec":
+		if doc := getPluginDocInfo("codec", name); doc != nil {
+			return doc.Description
+		}
+	case "option":
+		if sectionType == "codec" {
+			if doc := getPluginDocInfo("codec", pluginName); doc != nil && doc.Options[name] != nil {
+				return doc.Options[name].Description
+			}
+			return ""
+		}
+		if doc := getOptionDocInfo(sectionType, pluginName, name); doc != nil {
+			return doc.Description
+		}
+	}
+	return ""
+}
+
+// descriptionSnippet shortens a description to its first sentence, or its
+// first line when that comes sooner, cut at a word boundary to at most
+// maxSnippetLength bytes.
+func descriptionSnippet(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i+1]
+	}
+	if len(s) > maxSnippetLength {
+		cut := strings.LastIndexByte(s[:maxSnippetLength], ' ')
+		if cut <= 0 {
+			cut = maxSnippetLength
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+		}
+		s = s[:cut] + "…"
+	}
+	return s
+}
+
+// getSearchResults is the WASM entry point for the registry search box.
+// Args: query. Returns [{kind, name, sectionType, pluginName, description,
+// score}, ...].
+func getSearchResults(this js.Value, args []js.Value) interface{} {
+	results := []searchResult{}
+	if len(args) >= 1 {
+		results = append(results, searchRegistry(args[0].String())...)
+	}
+	b, _ := json.Marshal(results)
+	return string(b)
+}
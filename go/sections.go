@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"syscall/js"
+
+	config "github.com/breml/logstash-config"
+)
+
+// sectionRange locates a top-level input/filter/output block in the source.
+type sectionRange struct {
+	SectionType string // "input", "filter", "output"
+	Start       int    // offset of the section keyword
+	Open        int    // offset of the opening {
+	Close       int    // offset of the matching }, -1 if unclosed
+}
+
+// findSectionRanges scans the source for top-level section blocks, skipping
+// braces inside strings and comments.
+func findSectionRanges(source string) []sectionRange {
+	var ranges []sectionRange
+	depth := 0
+	current := -1 // index into ranges of the open top-level section
+
+	i := 0
+	for i < len(source) {
+		ch := source[i]
+
+		switch {
+		case ch == '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+			continue
+
+		case ch == '"' || ch == '\'':
+			i++
+			for i < len(source) && source[i] != ch {
+				if source[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			continue
+
+		case ch == '{':
+			depth++
+
+		case ch == '}':
+			if depth > 0 {
+				depth--
+				if depth == 0 && current >= 0 {
+					ranges[current].Close = i
+					current = -1
+				}
+			}
+
+		case depth == 0 && isIdentStart(ch):
+			start := i
+			for i < len(source) && isIdentChar(source[i]) {
+				i++
+			}
+			ident := source[start:i]
+			j := i
+			for j < len(source) && isBlank(source[j]) {
+				j++
+			}
+			if j < len(source) && source[j] == '{' && pluginTypeMap[ident] != 0 {
+				ranges = append(ranges, sectionRange{SectionType: ident, Start: start, Open: j, Close: -1})
+				current = len(ranges) - 1
+				depth = 1
+				i = j + 1
+			}
+			continue
+		}
+		i++
+	}
+	return ranges
+}
+
+// sectionStartRegex matches a section keyword and brace at the start of a
+// line, where top-level sections begin in conventionally formatted configs.
+var sectionStartRegex = regexp.MustCompile(`(?m)^(input|filter|output)\s*\{`)
+
+// sectionSyntaxDiagnostics splits input before each line starting a section
+// and parses the parts independently, returning syntax diagnostics for every
+// part that fails, with offsets and quoted positions in input. Unlike findSectionRanges this
+// doesn't rely on brace matching, so an unclosed section doesn't swallow the
+// ones after it. It returns nil when there is nothing to split.
+func sectionSyntaxDiagnostics(input string) []Diagnostic {
+	starts := sectionStarts(input)
+	if len(starts) < 2 {
+		return nil
+	}
+
+	var diags []Diagnostic
+	for i, start := range starts {
+		end := len(input)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if _, err := config.Parse("", []byte(input[start:end])); err != nil {
+			diags = append(diags, parseErrorDiagnostics(input, err, start)...)
+		}
+	}
+	return diags
+}
+
+// sectionStarts returns the offsets input is split at for per-section
+// parsing: each line starting a section, and 0 if there is stray text before
+// the first one.
+func sectionStarts(input string) []int {
+	var starts []int
+	for _, loc := range sectionStartRegex.FindAllStringIndex(input, -1) {
+		starts = append(starts, loc[0])
+	}
+	if len(starts) > 0 && strings.TrimSpace(input[:starts[0]]) != "" {
+		starts = append([]int{0}, starts...) // stray text before the first section
+	}
+	return starts
+}
+
+// insertPosition describes where to insert a new plugin into a section.
+// The caller inserts Before + <plugin text> + After at Offset.
+type insertPosition struct {
+	OK      bool   `json:"ok"`
+	Offset  int    `json:"offset"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Created bool   `json:"created"` // true if Before/After create the section
+}
+
+// insertPositionFor returns where a plugin for sectionType should go: at the
+// end of the last existing section of that type, or in a new section placed
+// in input → filter → output order.
+func insertPositionFor(source, sectionType string) insertPosition {
+	ranges := findSectionRanges(source)
+
+	for i := len(ranges) - 1; i >= 0; i-- {
+		r := ranges[i]
+		if r.SectionType != sectionType || r.Close < 0 {
+			continue
+		}
+		lineStart := strings.LastIndexByte(source[:r.Close], '\n') + 1
+		if lineStart > r.Open && strings.TrimSpace(source[lineStart:r.Close]) == "" {
+			// Closing brace on its own line: insert a new line above it
+			return insertPosition{Offset: lineStart, Before: "  ", After: "\n"}
+		}
+		return insertPosition{Offset: r.Close, Before: "\n  ", After: "\n"}
+	}
+
+	// Section missing: create it before the first section that must follow it.
+	order := map[string]int{"input": 0, "filter": 1, "output": 2}
+	for _, r := range ranges {
+		if order[r.SectionType] > order[sectionType] {
+			return insertPosition{
+				Offset:  r.Start,
+				Before:  sectionType + " {\n  ",
+				After:   "\n}\n\n",
+				Created: true,
+			}
+		}
+	}
+
+	before := sectionType + " {\n  "
+	if source != "" {
+		before = "\n" + before
+		if !strings.HasSuffix(source, "\n") {
+			before = "\n" + before
+		}
+	}
+	return insertPosition{Offset: len(source), Before: before, After: "\n}\n", Created: true}
+}
+
+// getInsertPosition is the WASM entry point for "add plugin to section".
+// Args: source, sectionType ("input", "filter", "output").
+func getInsertPosition(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": "source and section type required"})
+		return string(b)
+	}
+
+	source := args[0].String()
+	sectionType := args[1].String()
+	if pluginTypeMap[sectionType] == 0 {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": "unknown section type " + sectionType})
+		return string(b)
+	}
+
+	pos := insertPositionFor(source, sectionType)
+	pos.OK = true
+	b, _ := json.Marshal(pos)
+	return string(b)
+}
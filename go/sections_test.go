@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestSectionSyntaxDiagnostics(t *testing.T) {
+	src := "input { stdin { } }\n" +
+		"filter {\n" +
+		"  mutate { add_tag => [\"a\" }\n" +
+		"}\n" +
+		"output {\n" +
+		"  stdout { codec => }\n" +
+		"}\n"
+	want := []Diagnostic{
+		{From: 56, To: 57, Message: "Parsing error at pos 3:27 [55] and [56] (after: ' '): expect closing square bracket"},
+		{From: 80, To: 81, Message: "Parsing error at pos 6:11 [79] and [80] (after: ' '): expect closing curly bracket"},
+	}
+
+	result := parseAndValidate(src, parseOptions{AllErrors: true})
+	if result.OK {
+		t.Fatal("broken config parsed")
+	}
+	got := result.Diagnostics
+	if len(got) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.From != w.From || g.To != w.To || g.Message != w.Message || g.Severity != "error" || g.Code != codeSyntaxError {
+			t.Errorf("diagnostic %d = %+v, want [%d, %d) %q", i, g, w.From, w.To, w.Message)
+		}
+	}
+}
+
+func TestParseErrorSingleResult(t *testing.T) {
+	src := "filter {\n  mutate { add_tag => [\"a\" }\n}\noutput {\n  stdout { codec => }\n}\n"
+	got := parseAndValidate(src, parseOptions{}).Diagnostics
+	if len(got) != 1 {
+		t.Fatalf("got %d diagnostics, want the first error only: %+v", len(got), got)
+	}
+	if want := "Parsing error at pos 2:27 [35] and [36] (after: ' '): expect closing square bracket"; got[0].Message != want {
+		t.Errorf("message = %q, want %q", got[0].Message, want)
+	}
+}
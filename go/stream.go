@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"syscall/js"
+)
+
+// Configs too large to pass as a single JS string are streamed in chunks:
+// validateStreamBegin → validateStreamChunk (repeated) → validateStreamEnd.
+// Chunks are concatenated before parsing, so diagnostic offsets refer to the
+// full document. A page that abandons streams without ending them can't
+// grow memory without bound: beyond maxOpenStreams, opening one evicts the
+// oldest.
+var (
+	streams      = map[int]*strings.Builder{}
+	nextStreamID = 1
+)
+
+const maxOpenStreams = 8
+
+// openStream registers an empty stream, evicting the oldest open one when
+// maxOpenStreams are already open, and returns its id.
+func openStream() int {
+	if len(streams) >= maxOpenStreams {
+		delete(streams, slices.Min(slices.Collect(maps.Keys(streams))))
+	}
+	id := nextStreamID
+	nextStreamID++
+	streams[id] = &strings.Builder{}
+	return id
+}
+
+// validateStreamBegin opens a new stream and returns {"id": n}.
+func validateStreamBegin(this js.Value, args []js.Value) interface{} {
+	id := openStream()
+
+	b, _ := json.Marshal(map[string]interface{}{"id": id})
+	return string(b)
+}
+
+// validateStreamChunk appends a chunk to a stream. Args: id, chunk.
+func validateStreamChunk(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": "stream id and chunk required"})
+		return string(b)
+	}
+	buf, ok := streams[args[0].Int()]
+	if !ok {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": "unknown stream"})
+		return string(b)
+	}
+	buf.WriteString(args[1].String())
+
+	b, _ := json.Marshal(map[string]interface{}{"ok": true})
+	return string(b)
+}
+
+// validateStreamEnd closes a stream and returns the ParseResult for the
+// accumulated input. Args: id.
+func validateStreamEnd(this js.Value, args []js.Value) interface{} {
+	var buf *strings.Builder
+	if len(args) > 0 {
+		buf = streams[args[0].Int()]
+		delete(streams, args[0].Int())
+	}
+	if buf == nil {
+		return marshal(ParseResult{OK: false, Diagnostics: []Diagnostic{
+			{From: 0, To: 0, Severity: "error", Message: "unknown stream", Code: codeSyntaxError},
+		}})
+	}
+	return marshal(parseAndValidate(buf.String(), parseOptions{}))
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestOpenStreamEvictsOldest(t *testing.T) {
+	t.Cleanup(func() { clear(streams) })
+
+	first := openStream()
+	for range maxOpenStreams {
+		openStream()
+	}
+	if len(streams) != maxOpenStreams {
+		t.Errorf("%d streams open, want at most %d", len(streams), maxOpenStreams)
+	}
+	if _, ok := streams[first]; ok {
+		t.Errorf("oldest stream %d not evicted", first)
+	}
+	if _, ok := streams[first+1]; !ok {
+		t.Errorf("stream %d evicted, want only the oldest", first+1)
+	}
+}
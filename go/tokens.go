@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"syscall/js"
+
+	config "github.com/breml/logstash-config"
+	"github.com/breml/logstash-config/ast"
+)
+
+// semanticToken is a classified span of the source for registry-aware
+// highlighting. Type is one of "section", "plugin", "plugin-unknown",
+// "option", "option-unknown", "codec", "value" or "field-ref".
+type semanticToken struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Type string `json:"type"`
+}
+
+// semanticTokens classifies the source from its AST, with plugins and
+// options checked against the registry like validatePlugin does. Sources
+// that don't parse get a lexical scan instead (see lexicalTokens), so
+// highlighting survives typing. Tokens are sorted by From.
+func semanticTokens(input string) []semanticToken {
+	parsed, err := config.Parse("", []byte(input))
+	if err != nil {
+		return lexicalTokens(input)
+	}
+	cfg, ok := parsed.(ast.Config)
+	if !ok {
+		return lexicalTokens(input)
+	}
+
+	t := &tokenWalker{input: input}
+	for _, sections := range [][]ast.PluginSection{cfg.Input, cfg.Filter, cfg.Output} {
+		for _, section := range sections {
+			from := section.Pos().Offset
+			t.add(from, from+len(pluginTypeString(section.PluginType)), "section")
+			t.block(section.BranchOrPlugins, section.PluginType)
+		}
+	}
+	sort.SliceStable(t.tokens, func(i, j int) bool { return t.tokens[i].From < t.tokens[j].From })
+	return t.tokens
+}
+
+type tokenWalker struct {
+	input  string
+	tokens []semanticToken
+}
+
+func (t *tokenWalker) add(from, to int, typ string) {
+	from, to = clampFrom(from, t.input), clampTo(to, t.input)
+	if to > from {
+		t.tokens = append(t.tokens, semanticToken{From: from, To: to, Type: typ})
+	}
+}
+
+func (t *tokenWalker) block(block []ast.BranchOrPlugin, pt ast.PluginType) {
+	for _, bop := range block {
+		switch node := bop.(type) {
+		case ast.Plugin:
+			t.plugin(node, pt)
+		case ast.Branch:
+			t.condition(node.IfBlock.Condition)
+			t.block(node.IfBlock.Block, pt)
+			for _, elseIf := range node.ElseIfBlock {
+				t.condition(elseIf.Condition)
+				t.block(elseIf.Block, pt)
+			}
+			t.block(node.ElseBlock.Block, pt)
+		}
+	}
+}
+
+func (t *tokenWalker) plugin(plugin ast.Plugin, pt ast.PluginType) {
+	name := plugin.Name()
+	known := true
+	if plugins, ok := knownPlugins[pt]; ok {
+		known = plugins[name]
+	}
+	from := plugin.Pos().Offset
+	typ := "plugin"
+	if !known {
+		typ = "plugin-unknown"
+	}
+	t.add(from, from+len(name), typ)
+
+	knownOpts := getPluginOptions(pt, name)
+	for _, attr := range plugin.Attributes {
+		from := attr.Pos().Offset
+		typ := "option"
+		if known && knownOpts != nil && !knownOpts[optionName(attr)] {
+			typ = "option-unknown"
+		}
+		t.add(from, from+len(attr.Name()), typ)
+
+		if isCodecOption(pluginTypeString(pt), name, optionName(attr)) {
+			if pa, ok := attr.(ast.PluginAttribute); ok {
+				from := valueOffset(attr.Pos().Offset, t.input)
+				t.add(from, from+len(extractCodecName(pa.ValueString())), "codec")
+			} else {
+				from, to := valueRange(attr, t.input)
+				t.add(from, to, "codec")
+			}
+			continue
+		}
+		from, to := valueRange(attr, t.input)
+		t.value(attr, from, to)
+	}
+}
+
+// value adds the tokens of a value spanning [from, to); for arrays and
+// hashes only their elements, which carry their own positions, count.
+func (t *tokenWalker) value(v ast.Attribute, from, to int) {
+	switch v := v.(type) {
+	case ast.StringAttribute, ast.NumberAttribute:
+		t.add(from, to, "value")
+	case ast.ArrayAttribute:
+		for _, el := range v.Attributes {
+			t.nested(el)
+		}
+	case ast.HashAttribute:
+		for _, entry := range v.Entries {
+			if key, ok := entry.Key.(ast.StringAttribute); ok {
+				t.nested(key)
+			} else {
+				from := entry.Key.Pos().Offset
+				t.add(from, from+len(entry.Name()), "value")
+			}
+			from, to := entryValueRange(entry, t.input)
+			t.value(entry.Value, from, to)
+		}
+	}
+}
+
+// nested adds a value inside an array or hash, whose position is its first
+// character (the opening quote of a quoted string). The parser leaves
+// nested numbers, and some lists, without a position; numbers are skipped
+// and lists only need their elements'.
+func (t *tokenWalker) nested(v ast.Attribute) {
+	if _, ok := v.(ast.NumberAttribute); ok && v.Pos().Line == 0 {
+		return
+	}
+	from := v.Pos().Offset
+	t.value(v, from, from+valueLength(v, t.input, from))
+}
+
+func (t *tokenWalker) condition(cond ast.Condition) {
+	for _, expr := range cond.Expression {
+		switch e := expr.(type) {
+		case ast.ConditionExpression:
+			t.condition(e.Condition)
+		case ast.NegativeConditionExpression:
+			t.condition(e.Condition)
+		case ast.NegativeSelectorExpression:
+			t.operand(e.Selector)
+		case ast.RvalueExpression:
+			t.operand(e.RValue)
+		case ast.CompareExpression:
+			t.operand(e.LValue)
+			t.operand(e.RValue)
+		case ast.RegexpExpression:
+			t.operand(e.LValue)
+			switch rv := e.RValue.(type) {
+			case ast.Regexp:
+				t.add(rv.Pos().Offset, rv.Pos().Offset+len(rv.ValueString()), "value")
+			case ast.StringAttribute:
+				t.operand(rv)
+			}
+		case ast.InExpression:
+			t.operand(e.LValue)
+			t.operand(e.RValue)
+		case ast.NotInExpression:
+			t.operand(e.LValue)
+			t.operand(e.RValue)
+		}
+	}
+}
+
+func (t *tokenWalker) operand(operand ast.Rvalue) {
+	switch v := operand.(type) {
+	case ast.Selector:
+		t.add(v.Pos().Offset, v.Pos().Offset+len(v.String()), "field-ref")
+	case ast.Attribute:
+		t.nested(v)
+	}
+}
+
+// lexicalTokens classifies a source that doesn't parse by scanning it,
+// tracking blocks the way detectContext does: an identifier followed by {
+// opens a section (at the top level) or a plugin, one followed by => is an
+// option, and strings, numbers and regexes are values. Inside if/else if
+// conditions [field] references are field refs.
+func lexicalTokens(input string) []semanticToken {
+	t := &tokenWalker{input: input}
+	var stack []frame
+	inCondition := false // between if and its {
+	afterArrow := false  // the last token was =>
+	codecValue := false  // the last tokens were codec =>
+
+	i := 0
+	for i < len(input) {
+		ch := input[i]
+		switch {
+		case ch == '#':
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+			continue
+
+		case ch == '"' || ch == '\'' || ch == '/' && regexFollowsOperator(input, i):
+			start := i
+			i++
+			for i < len(input) && input[i] != ch {
+				if input[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			t.add(start, i, "value")
+			afterArrow, codecValue = false, false
+			continue
+
+		case ch == '[' && inCondition:
+			// [a][b], but not a list such as ["a", "b"]
+			start := i
+			for i < len(input) && input[i] == '[' {
+				end := i + 1
+				for end < len(input) && strings.IndexByte("]\n\"',", input[end]) < 0 {
+					end++
+				}
+				if end >= len(input) || input[end] != ']' {
+					break
+				}
+				i = end + 1
+			}
+			if i > start {
+				t.add(start, i, "field-ref")
+				continue
+			}
+
+		case ch == '=' && i+1 < len(input) && input[i+1] == '>':
+			afterArrow = true
+			i += 2
+			continue
+
+		case ch == '{':
+			kind := frameHash
+			if inCondition || !afterArrow && len(stack) > 0 {
+				kind = frameConditional
+			}
+			stack = append(stack, frame{kind: kind, sectionType: currentSectionType(stack)})
+			inCondition, afterArrow, codecValue = false, false, false
+
+		case ch == '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+		case isDigit(ch) || ch == '-' && i+1 < len(input) && isDigit(input[i+1]):
+			start := i
+			i++
+			for i < len(input) && (isDigit(input[i]) || input[i] == '.') {
+				i++
+			}
+			t.add(start, i, "value")
+			afterArrow, codecValue = false, false
+			continue
+
+		case isIdentStart(ch):
+			start := i
+			for i < len(input) && isIdentChar(input[i]) {
+				i++
+			}
+			ident := input[start:i]
+			next := skipBlanksAndComments(input, i)
+			t.lexicalIdent(ident, start, next, &stack, afterArrow, codecValue)
+
+			codecValue = !afterArrow && lexicalCodecOption(ident, stack)
+			afterArrow = false
+			switch {
+			case ident == "if" && !inCondition:
+				inCondition = true
+			case next < len(input) && input[next] == '{' && !inCondition:
+				// Push the block here so the { isn't taken for a hash.
+				stack = append(stack, t.lexicalFrame(ident, stack))
+				i = next + 1
+			}
+			continue
+		}
+		i++
+	}
+	return t.tokens
+}
+
+// lexicalIdent classifies an identifier at start; next is the offset of the
+// first character after it and any blanks.
+func (t *tokenWalker) lexicalIdent(ident string, start, next int, stack *[]frame, afterArrow, codecValue bool) {
+	end := start + len(ident)
+	opensBlock := next < len(t.input) && t.input[next] == '{'
+	top := currentFrameKind(*stack)
+
+	switch {
+	case codecValue && afterArrow:
+		t.add(start, end, "codec")
+	case afterArrow:
+		t.add(start, end, "value")
+	case opensBlock && len(*stack) == 0:
+		if _, ok := pluginTypeMap[ident]; ok {
+			t.add(start, end, "section")
+		}
+	case opensBlock && ident != "else" && (top == frameSection || top == frameConditional):
+		typ := "plugin"
+		if plugins, ok := knownPlugins[currentSectionType(*stack)]; ok && !plugins[ident] {
+			typ = "plugin-unknown"
+		}
+		t.add(start, end, typ)
+	case next+1 < len(t.input) && t.input[next] == '=' && t.input[next+1] == '>' && top == framePlugin:
+		f := (*stack)[len(*stack)-1]
+		typ := "option"
+		if plugins, ok := knownPlugins[f.sectionType]; !ok || plugins[f.pluginName] {
+			if known := getPluginOptions(f.sectionType, f.pluginName); known != nil && !known[ident] {
+				typ = "option-unknown"
+			}
+		}
+		t.add(start, end, typ)
+	}
+}
+
+// lexicalCodecOption reports whether ident, if it is an option of the
+// plugin whose block is open, takes a codec.
+func lexicalCodecOption(ident string, stack []frame) bool {
+	if currentFrameKind(stack) != framePlugin {
+		return ident == "codec"
+	}
+	f := stack[len(stack)-1]
+	return isCodecOption(pluginTypeString(f.sectionType), f.pluginName, ident)
+}
+
+// lexicalFrame returns the frame an identifier followed by { opens.
+func (t *tokenWalker) lexicalFrame(ident string, stack []frame) frame {
+	sectionType := currentSectionType(stack)
+	switch top := currentFrameKind(stack); {
+	case len(stack) == 0:
+		return frame{kind: frameSection, sectionType: pluginTypeMap[ident]}
+	case ident == "else":
+		return frame{kind: frameConditional, sectionType: sectionType}
+	case top == frameSection || top == frameConditional:
+		return frame{kind: framePlugin, sectionType: sectionType, pluginName: ident}
+	}
+	return frame{kind: frameHash, sectionType: sectionType}
+}
+
+// getSemanticTokens is the WASM entry point for registry-aware
+// highlighting. Args: source. Returns [{from, to, type}, ...].
+func getSemanticTokens(this js.Value, args []js.Value) interface{} {
+	tokens := []semanticToken{}
+	if len(args) >= 1 {
+		tokens = append(tokens, semanticTokens(args[0].String())...)
+	}
+	b, _ := json.Marshal(tokens)
+	return string(b)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	config "github.com/breml/logstash-config"
+	"github.com/breml/logstash-config/ast"
+)
+
+// validateResult is the JSON response of validateLogstashConfig.
+type validateResult struct {
+	OK          bool         `json:"ok"` // the whole config parsed
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Plugins     int          `json:"plugins"`
+	Options     int          `json:"options"`
+}
+
+// validateConfig validates as much of input as parses. If the whole config
+// doesn't, each section (see sectionStarts) is parsed on its own: broken
+// ones report syntax errors and the rest are validated, so a typo in one
+// section doesn't hide problems in the others. Checks across sections, such
+// as duplicate sections, only see what parsed together.
+func validateConfig(input string) validateResult {
+	result := validateResult{Diagnostics: []Diagnostic{}}
+	parsed, err := config.Parse("", []byte(input))
+	if err == nil {
+		result.OK = true
+		if cfg, ok := parsed.(ast.Config); ok {
+			result.Diagnostics = validate(cfg, input)
+			result.countConfig(cfg)
+		}
+		return result
+	}
+
+	starts := sectionStarts(input)
+	if len(starts) < 2 {
+		result.Diagnostics = parseErrorDiagnostics(input, err, 0)
+		return result
+	}
+	for i, start := range starts {
+		end := len(input)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		part := input[start:end]
+		parsed, err := config.Parse("", []byte(part))
+		if err != nil {
+			result.Diagnostics = append(result.Diagnostics, parseErrorDiagnostics(input, err, start)...)
+			continue
+		}
+		if cfg, ok := parsed.(ast.Config); ok {
+			for _, d := range validate(cfg, part) {
+				result.Diagnostics = append(result.Diagnostics, shiftDiagnostic(d, start))
+			}
+			result.countConfig(cfg)
+		}
+	}
+	return result
+}
+
+// countConfig adds the plugins in cfg and the options set on them.
+func (r *validateResult) countConfig(cfg ast.Config) {
+	for _, sections := range [][]ast.PluginSection{cfg.Input, cfg.Filter, cfg.Output} {
+		for _, section := range sections {
+			forEachPlugin(section.BranchOrPlugins, func(plugin ast.Plugin) {
+				r.Plugins++
+				r.Options += len(plugin.Attributes)
+			})
+		}
+	}
+}
+
+// shiftDiagnostic moves a diagnostic, and its fix, by offset.
+func shiftDiagnostic(d Diagnostic, offset int) Diagnostic {
+	d.From += offset
+	d.To += offset
+	if d.Fix != nil {
+		fix := *d.Fix
+		fix.From += offset
+		fix.To += offset
+		d.Fix = &fix
+	}
+	return d
+}
+
+// validateLogstashConfig is the WASM entry point for standalone validation,
+// for callers other than the editor such as a CLI or test harness.
+// Args: source. Returns {ok, diagnostics, plugins, options}.
+func validateLogstashConfig(this js.Value, args []js.Value) interface{} {
+	result := validateResult{Diagnostics: []Diagnostic{
+		{From: 0, To: 1, Severity: "error", Message: "no input provided", Code: codeSyntaxError},
+	}}
+	if len(args) > 0 {
+		input := args[0].String()
+		result = validateConfig(input)
+		newLineIndex(input).locateAll(result.Diagnostics)
+	}
+	b, _ := json.Marshal(result)
+	return string(b)
+}
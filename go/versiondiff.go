@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"syscall/js"
+)
+
+// nameDiff lists names present in only one of two versions.
+type nameDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (d nameDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// versionDiff is the JSON response of diffLogstashVersions. Options are
+// compared for plugins present in both versions, keyed like "filter/grok".
+type versionDiff struct {
+	OK      bool                `json:"ok"`
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Plugins map[string]nameDiff `json:"plugins"` // key: "input", "filter", "output"
+	Codecs  nameDiff            `json:"codecs"`
+	Options map[string]nameDiff `json:"options"`
+}
+
+// diffNames returns the names added in and removed from b relative to a,
+// sorted.
+func diffNames(a, b []string) nameDiff {
+	inA := make(map[string]bool, len(a))
+	for _, n := range a {
+		inA[n] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, n := range b {
+		inB[n] = true
+	}
+	d := nameDiff{Added: []string{}, Removed: []string{}}
+	for _, n := range b {
+		if !inA[n] {
+			d.Added = append(d.Added, n)
+		}
+	}
+	for _, n := range a {
+		if !inB[n] {
+			d.Removed = append(d.Removed, n)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	return d
+}
+
+// diffRegistries compares two registries, from a to b.
+func diffRegistries(a, b *registryData) versionDiff {
+	diff := versionDiff{
+		OK:      true,
+		From:    a.Version,
+		To:      b.Version,
+		Plugins: map[string]nameDiff{},
+		Codecs:  diffNames(a.Codecs, b.Codecs),
+		Options: map[string]nameDiff{},
+	}
+	for typeName := range pluginTypeMap {
+		diff.Plugins[typeName] = diffNames(a.Plugins[typeName], b.Plugins[typeName])
+	}
+	for key, opts := range b.PluginOptions {
+		old, ok := a.PluginOptions[key]
+		if !ok {
+			continue // a new plugin, or no schema before
+		}
+		if d := diffNames(old, opts); !d.empty() {
+			diff.Options[key] = d
+		}
+	}
+	return diff
+}
+
+// diffLogstashVersions is the WASM entry point comparing two registry
+// versions, for migration planning. The active version is unchanged.
+// Args: from version, to version.
+func diffLogstashVersions(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": "two versions required"})
+		return string(b)
+	}
+	from, err := readRegistry(args[0].String())
+	if err == nil {
+		var to *registryData
+		if to, err = readRegistry(args[1].String()); err == nil {
+			b, _ := json.Marshal(diffRegistries(from, to))
+			return string(b)
+		}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"ok": false, "error": err.Error()})
+	return string(b)
+}
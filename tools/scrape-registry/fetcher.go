@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fetcher performs the scraper's GitHub requests. Raw fetches from
+// raw.githubusercontent.com, which has no rate limit; API from the GitHub
+// API, which does. A missing file is an *httpStatusError with code 404.
+type Fetcher interface {
+	Raw(url string) ([]byte, error)
+	API(url string) ([]byte, error)
+}
+
+// sourceFetcher reads the files extraction works from: Logstash's Gemfile
+// lockfile and the contents of plugin repositories at a gem version.
+type sourceFetcher interface {
+	// lockfile returns the named lockfile of Logstash at a version tag.
+	lockfile(version, name string) ([]byte, error)
+	// pluginFile returns a file from a plugin repository; path is relative
+	// to the repository root.
+	pluginFile(repo, version, path string) ([]byte, error)
+	// repoTree lists every file and directory of a plugin repository.
+	repoTree(repo, version string) ([]treeEntry, error)
+	// location names where pluginFile reads path from, for logs and reports.
+	location(repo, version, path string) string
+}
+
+// githubFetcher reads from GitHub through a Fetcher: files through
+// raw.githubusercontent.com, trees through the API.
+type githubFetcher struct {
+	net Fetcher
+
+	// Repo trees by repo@version, to avoid duplicate API calls.
+	trees   map[string][]treeEntry
+	treesMu sync.Mutex
+}
+
+func newGithubFetcher(net Fetcher) *githubFetcher {
+	return &githubFetcher{net: net, trees: map[string][]treeEntry{}}
+}
+
+func (f *githubFetcher) lockfile(version, name string) ([]byte, error) {
+	return f.net.Raw(fmt.Sprintf("https://raw.githubusercontent.com/elastic/logstash/v%s/%s", version, name))
+}
+
+func (f *githubFetcher) pluginFile(repo, version, path string) ([]byte, error) {
+	return f.net.Raw(f.location(repo, version, path))
+}
+
+func (f *githubFetcher) repoTree(repo, version string) ([]treeEntry, error) {
+	key := repo + "@" + version
+	f.treesMu.Lock()
+	cached, ok := f.trees[key]
+	f.treesMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	tree, err := getRepoTree(f.net, repo, version)
+	if err != nil {
+		return nil, err
+	}
+	f.treesMu.Lock()
+	f.trees[key] = tree
+	f.treesMu.Unlock()
+	return tree, nil
+}
+
+func (*githubFetcher) location(repo, version, path string) string {
+	return fmt.Sprintf("https://raw.githubusercontent.com/logstash-plugins/%s/v%s/%s", repo, version, path)
+}
+
+// httpFetcher is the live Fetcher. Both methods go through the -cache disk
+// cache and retry transient failures (see cachedFetch).
+type httpFetcher struct {
+	token    string
+	apiDelay time.Duration
+
+	lastAPICall time.Time
+	apiMu       sync.Mutex // guards lastAPICall
+}
+
+func newHTTPFetcher(token string) *httpFetcher {
+	f := &httpFetcher{token: token, apiDelay: 100 * time.Millisecond}
+	if token != "" {
+		f.apiDelay = 20 * time.Millisecond // faster with auth
+	}
+	return f
+}
+
+// Raw fetches from raw.githubusercontent.com (no API rate limit).
+func (f *httpFetcher) Raw(url string) ([]byte, error) {
+	return cachedFetch(url, f.rawUncached)
+}
+
+func (f *httpFetcher) rawUncached(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "token "+f.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, &httpStatusError{code: resp.StatusCode, url: url}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// API fetches from the GitHub API with rate limiting. Cache hits skip the
+// rate limiting.
+func (f *httpFetcher) API(url string) ([]byte, error) {
+	return cachedFetch(url, f.apiUncached)
+}
+
+func (f *httpFetcher) apiUncached(url string) ([]byte, error) {
+	// Reserve the next slot apiDelay after the previous call, then wait for it.
+	f.apiMu.Lock()
+	slot := f.lastAPICall.Add(f.apiDelay)
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	f.lastAPICall = slot
+	f.apiMu.Unlock()
+	time.Sleep(time.Until(slot))
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "token "+f.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 403 {
+		body, _ := io.ReadAll(resp.Body)
+		if strings.Contains(string(body), "rate limit") {
+			rl := &rateLimitError{}
+			if secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				rl.reset = time.Unix(secs, 0)
+			}
+			return nil, rl
+		}
+		return nil, fmt.Errorf("HTTP 403 for %s: %s", url, string(body))
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, &httpStatusError{code: resp.StatusCode, url: url}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// localFetcher reads from a Logstash checkout (or distribution) on disk,
+// enabled by -source. The lockfile is at the root and plugins are the
+// installed gems under vendor/bundle/jruby/*/gems. The checkout is a single
+// version, so the version asked for only picks the gem directory.
+type localFetcher struct {
+	dir string
+}
+
+func (f localFetcher) lockfile(_, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(f.dir, name))
+}
+
+func (f localFetcher) pluginFile(repo, version, path string) ([]byte, error) {
+	gemDir, err := f.gemDir(repo, version)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(gemDir, filepath.FromSlash(path)))
+}
+
+func (f localFetcher) repoTree(repo, version string) ([]treeEntry, error) {
+	gemDir, err := f.gemDir(repo, version)
+	if err != nil {
+		return nil, err
+	}
+	var tree []treeEntry
+	err = filepath.WalkDir(gemDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == gemDir {
+			return err
+		}
+		rel, err := filepath.Rel(gemDir, p)
+		if err != nil {
+			return err
+		}
+		typ := "blob"
+		if d.IsDir() {
+			typ = "tree"
+		}
+		tree = append(tree, treeEntry{Path: filepath.ToSlash(rel), Type: typ})
+		return nil
+	})
+	return tree, err
+}
+
+func (f localFetcher) location(repo, version, path string) string {
+	if gemDir, err := f.gemDir(repo, version); err == nil {
+		return filepath.Join(gemDir, filepath.FromSlash(path))
+	}
+	return filepath.Join(f.dir, repo+"-"+version, filepath.FromSlash(path))
+}
+
+// gemDir finds the installed gem directory for repo at version. JRuby gems
+// may carry a -java platform suffix the lockfile version leaves out.
+func (f localFetcher) gemDir(repo, version string) (string, error) {
+	for _, name := range []string{repo + "-" + version, repo + "-" + version + "-java"} {
+		matches, err := filepath.Glob(filepath.Join(f.dir, "vendor", "bundle", "jruby", "*", "gems", name))
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	return "", fmt.Errorf("%s %s is not installed under %s: %w", repo, version, f.dir, fs.ErrNotExist)
+}
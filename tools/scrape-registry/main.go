@@ -4,36 +4,74 @@
 // Usage:
 //
 //	go run ./tools/scrape-registry -version 8.19 -out go/registrydata/8.19.json
+//
+// Incremental (only refetch plugins whose gem version changed):
+//
+//	go run ./tools/scrape-registry -version 8.19 -out go/registrydata/8.19.json -since go/registrydata/8.18.json
+//
+// Cached (responses, including 404s, are reused from disk for -cache-ttl):
+//
+//	go run ./tools/scrape-registry -version 8.19 -out go/registrydata/8.19.json -cache /tmp/scrape-cache
+//
+// With -docs, options the Ruby source leaves undocumented take their
+// description from the plugin's AsciiDoc docs page (about twice the fetches).
+// With -full-descriptions, plugin docs also carry the whole class comment as
+// longDescription, not just its first paragraph. With -gzip, a gzipped copy
+// is written next to the output (8.19.json.gz), which the WASM parser can
+// embed instead of the plain file.
+// With -strict, the registry is sanity-checked (non-empty sections, no
+// duplicates, well-formed option names and types) and not written if a
+// check fails.
+//
+// Offline, from a Logstash checkout with its plugin gems installed
+// (vendor/bundle), instead of GitHub:
+//
+//	go run ./tools/scrape-registry -version 8.19 -out go/registrydata/8.19.json -source ~/src/logstash
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
-	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 // OptionDoc holds rich documentation for a single config option.
 type OptionDoc struct {
-	Type        string `json:"type,omitempty"`
-	Required    bool   `json:"required,omitempty"`
-	Default     string `json:"default,omitempty"`
-	Description string `json:"description,omitempty"`
-	Deprecated  string `json:"deprecated,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Deprecated  string   `json:"deprecated,omitempty"`
+	Conflicts   []string `json:"conflicts,omitempty"` // options that can't be set together with this one
+	Min         *float64 `json:"min,omitempty"`       // lowest valid number, for number options
+	Max         *float64 `json:"max,omitempty"`       // highest valid number, for number options
+	HashKeys    []string `json:"hashKeys,omitempty"`  // example keys, for hash options
+	Source      string   `json:"source,omitempty"`    // where it's declared: "plugin" or "mixin:plugin_mixins/<name>"
 }
 
 // PluginDoc holds rich documentation for a plugin.
 type PluginDoc struct {
-	Description string                `json:"description,omitempty"`
-	Options     map[string]*OptionDoc `json:"options,omitempty"`
+	Description     string                `json:"description,omitempty"`
+	LongDescription string                `json:"longDescription,omitempty"` // paragraphs separated by blank lines
+	Options         map[string]*OptionDoc `json:"options,omitempty"`
+	Constraint      string                `json:"constraint,omitempty"` // the gemspec's logstash-core requirement, e.g. ">= 8.10.0"
 }
 
 // RegistryData is the output JSON structure.
@@ -46,6 +84,7 @@ type RegistryData struct {
 	PluginDocs       map[string]*PluginDoc            `json:"pluginDocs,omitempty"`
 	CodecDocs        map[string]*PluginDoc            `json:"codecDocs,omitempty"`
 	CommonOptionDocs map[string]map[string]*OptionDoc `json:"commonOptionDocs,omitempty"`
+	PluginVersions   map[string]string                `json:"pluginVersions,omitempty"` // key: "input/beats" -> gem version
 }
 
 type gemInfo struct {
@@ -81,21 +120,71 @@ var (
 	defaultRegex        = regexp.MustCompile(`:default\s*=>\s*(.+?)(?:\s*,\s*:|$)`)
 	listRegex           = regexp.MustCompile(`:list\s*=>\s*true`)
 	obsoleteRegex       = regexp.MustCompile(`:obsolete\s*=>`)
-	deprecatedRegex     = regexp.MustCompile(`:deprecated\s*=>\s*["'](.+?)["']`)
+	deprecatedRegex     = regexp.MustCompile(`:deprecated\s*=>\s*(?:"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)')`)
+	rubyEscapeRegex     = regexp.MustCompile(`\\(.)`)
 	classRegex          = regexp.MustCompile(`class\s+LogStash::`)
 
-	token       string
-	apiDelay    = 100 * time.Millisecond
-	lastAPICall time.Time
+	// Retries of transient fetch failures.
+	fetchAttempts    = 3
+	retryBaseDelay   = 1 * time.Second
+	maxRateLimitWait = 5 * time.Minute
+
+	// On-disk HTTP cache, enabled by -cache.
+	cacheDir string
+	cacheTTL time.Duration
 
-	// Cache repo trees to avoid duplicate API calls for the same repo+version.
-	treeCache = map[string][]treeEntry{}
+	// Fall back to the AsciiDoc docs for option descriptions, enabled by -docs.
+	docsFallback bool
+	// Keep the full plugin description, enabled by -full-descriptions.
+	fullDescriptions bool
+
+	// AsciiDoc docs extraction
+	asciidocOptionIDRegex = regexp.MustCompile(`^\[id="[^"]*-(\w+)"\]\s*$`)
+	asciidocLinkRegex     = regexp.MustCompile(`https?://[^\[]+\[([^\]]+)\]`)
+	asciidocXrefRegex     = regexp.MustCompile(`<<([^,>]+)(?:,([^>]*))?>>`)
 )
 
+// httpStatusError is a non-200 response.
+type httpStatusError struct {
+	code int
+	url  string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d for %s", e.code, e.url)
+}
+
+// rateLimitError is a GitHub API rate limit response. reset is when the
+// limit resets, zero if the response didn't say.
+type rateLimitError struct {
+	reset time.Time
+}
+
+func (e *rateLimitError) Error() string {
+	return "GitHub API rate limit exceeded. Set GITHUB_TOKEN env var or use -token flag"
+}
+
+// isNotFound reports whether err is a 404 response, or a missing file with
+// -source.
+func isNotFound(err error) bool {
+	var se *httpStatusError
+	return errors.As(err, &se) && se.code == 404 || errors.Is(err, fs.ErrNotExist)
+}
+
 func main() {
 	version := flag.String("version", "", "Logstash version to scrape (e.g. 8.19)")
 	out := flag.String("out", "", "Output JSON file path")
 	tokenFlag := flag.String("token", "", "GitHub token (or use GITHUB_TOKEN env)")
+	since := flag.String("since", "", "Previous registry JSON; plugins with an unchanged gem version are copied instead of refetched")
+	concurrency := flag.Int("concurrency", 4, "Number of plugins fetched in parallel")
+	reportPath := flag.String("report", "", "Write a JSON extraction report to this file")
+	gzipOut := flag.Bool("gzip", false, "Also write a gzipped copy of the output to <out>.gz")
+	strict := flag.Bool("strict", false, "Check the assembled registry for extraction bugs and fail instead of writing it")
+	sourceDir := flag.String("source", "", "Read the lockfile and plugin sources from a local Logstash checkout instead of GitHub")
+	flag.StringVar(&cacheDir, "cache", "", "Directory for an on-disk HTTP cache (off when empty)")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 24*time.Hour, "How long cached responses are reused")
+	flag.BoolVar(&docsFallback, "docs", false, "Fill missing option descriptions from the plugin's AsciiDoc docs")
+	flag.BoolVar(&fullDescriptions, "full-descriptions", false, "Also store each plugin's multi-paragraph description")
 	flag.Parse()
 
 	if *version == "" || *out == "" {
@@ -103,18 +192,35 @@ func main() {
 		os.Exit(1)
 	}
 
-	token = *tokenFlag
+	token := *tokenFlag
 	if token == "" {
 		token = os.Getenv("GITHUB_TOKEN")
 	}
-	if token != "" {
-		apiDelay = 20 * time.Millisecond // faster with auth
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			log.Fatalf("Failed to create cache directory: %v", err)
+		}
+	}
+
+	var prev *RegistryData
+	if *since != "" {
+		var err error
+		if prev, err = loadPreviousRegistry(*since); err != nil {
+			log.Fatalf("Failed to load previous registry: %v", err)
+		}
+		log.Printf("Incremental mode: reusing unchanged plugins from %s (%s)", *since, prev.Version)
+	}
+
+	var src sourceFetcher = newGithubFetcher(newHTTPFetcher(token))
+	if *sourceDir != "" {
+		src = localFetcher{dir: *sourceDir}
+		log.Printf("Reading sources from %s", *sourceDir)
 	}
 
 	log.Printf("Scraping Logstash %s plugin registry...", *version)
 
 	// Phase 1: fetch lockfile and parse gems
-	gems, err := fetchGems(*version)
+	gems, err := fetchGems(src, *version)
 	if err != nil {
 		log.Fatalf("Failed to fetch lockfile: %v", err)
 	}
@@ -135,7 +241,7 @@ func main() {
 
 	// Phase 2: resolve integration plugins using tree API (1 API call per integration)
 	for _, ig := range integrations {
-		subs, err := resolveIntegration(ig)
+		subs, err := resolveIntegration(src, ig)
 		if err != nil {
 			log.Printf("WARNING: failed to resolve integration %s: %v", ig.repo, err)
 			continue
@@ -159,36 +265,73 @@ func main() {
 	pluginOptions := map[string][]string{}
 	pluginDocs := map[string]*PluginDoc{}
 	codecDocs := map[string]*PluginDoc{}
+	pluginVersions := map[string]string{}
+	reused := 0
 
-	for key, g := range standalone {
+	// Reused plugins are handled inline; the rest are fetched by the pool.
+	keys := make([]string, 0, len(standalone))
+	for key := range standalone {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := &extractionReport{Version: *version}
+	var toFetch []gemInfo
+	for _, key := range keys {
+		g := standalone[key]
 		switch g.typ {
 		case "codec":
 			codecs = append(codecs, g.name)
 		case "input", "filter", "output":
 			plugins[g.typ] = append(plugins[g.typ], g.name)
 		}
+		pluginVersions[key] = g.version
 
-		// Phase 3: extract config options with rich data
-		richOpts, pluginDesc, err := extractRichOptions(g)
-		if err != nil {
-			log.Printf("WARNING: failed to extract options for %s: %v", key, err)
+		// Incremental mode: same gem version means the same schema
+		if prev != nil && prev.PluginVersions[key] == g.version {
+			if opts, ok := prev.PluginOptions[key]; ok {
+				pluginOptions[key] = opts
+			}
+			if g.typ == "codec" {
+				if doc, ok := prev.CodecDocs[g.name]; ok {
+					codecDocs[g.name] = doc
+				}
+			} else if doc, ok := prev.PluginDocs[key]; ok {
+				pluginDocs[key] = doc
+			}
+			reused++
+			report.Plugins = append(report.Plugins, pluginReport{Plugin: key, Status: "reused", Options: len(pluginOptions[key])})
 			continue
 		}
+		toFetch = append(toFetch, g)
+	}
+
+	// Phase 3: extract config options with rich data
+	results := extractAll(src, toFetch, *concurrency)
+	for i, g := range toFetch {
+		key := g.typ + "/" + g.name
+		r := results[i]
+		if r.err != nil {
+			log.Printf("WARNING: failed to extract options for %s: %v", key, r.err)
+			report.Plugins = append(report.Plugins, pluginReport{Plugin: key, Status: "failed", Error: r.err.Error()})
+			continue
+		}
+		report.Plugins = append(report.Plugins, pluginReport{Plugin: key, Status: "ok", Options: len(r.options)})
 
 		// Build name-only list (backward compat)
-		if len(richOpts) > 0 {
-			names := make([]string, len(richOpts))
-			for i, o := range richOpts {
+		if len(r.options) > 0 {
+			names := make([]string, len(r.options))
+			for i, o := range r.options {
 				names[i] = o.Name
 			}
 			pluginOptions[key] = names
 		}
 
 		// Build plugin doc with option docs
-		doc := &PluginDoc{Description: pluginDesc}
-		if len(richOpts) > 0 {
-			doc.Options = make(map[string]*OptionDoc, len(richOpts))
-			for _, o := range richOpts {
+		doc := &PluginDoc{Description: r.description, LongDescription: r.longDescription, Constraint: r.constraint}
+		if len(r.options) > 0 {
+			doc.Options = make(map[string]*OptionDoc, len(r.options))
+			for _, o := range r.options {
 				optDoc := o.Doc // copy
 				doc.Options[o.Name] = &optDoc
 			}
@@ -214,6 +357,10 @@ func main() {
 	// Common option docs (hardcoded descriptions for well-known base class options)
 	commonOptionDocs := buildCommonOptionDocs()
 
+	if err := applyOverrides(pluginDocs, codecDocs, commonOptionDocs); err != nil {
+		log.Fatalf("Failed to apply overrides: %v", err)
+	}
+
 	// Phase 4: write JSON
 	data := RegistryData{
 		Version: *version,
@@ -228,6 +375,16 @@ func main() {
 		PluginDocs:       pluginDocs,
 		CodecDocs:        codecDocs,
 		CommonOptionDocs: commonOptionDocs,
+		PluginVersions:   pluginVersions,
+	}
+
+	if *strict {
+		if problems := checkRegistry(&data); len(problems) > 0 {
+			for _, p := range problems {
+				log.Printf("STRICT: %s", p)
+			}
+			log.Fatalf("Registry failed %d strict check(s); not writing %s", len(problems), *out)
+		}
 	}
 
 	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
@@ -245,9 +402,19 @@ func main() {
 	}
 
 	log.Printf("Wrote %s (%d bytes)", *out, len(b))
+	if *gzipOut {
+		n, err := writeGzip(*out+".gz", b)
+		if err != nil {
+			log.Fatalf("Failed to write %s.gz: %v", *out, err)
+		}
+		log.Printf("Wrote %s.gz (%d bytes)", *out, n)
+	}
 	log.Printf("  inputs: %d, filters: %d, outputs: %d, codecs: %d",
 		len(plugins["input"]), len(plugins["filter"]), len(plugins["output"]), len(codecs))
 	log.Printf("  plugin option schemas: %d", len(pluginOptions))
+	if prev != nil {
+		log.Printf("  reused from previous registry: %d, refetched: %d", reused, len(standalone)-reused)
+	}
 	docsWithDesc := 0
 	for _, d := range pluginDocs {
 		if d.Description != "" {
@@ -260,6 +427,273 @@ func main() {
 		}
 	}
 	log.Printf("  plugins with descriptions: %d", docsWithDesc)
+
+	if *reportPath != "" {
+		report.finish(pluginDocs, codecDocs)
+		if err := writeReport(*reportPath, report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		log.Printf("Wrote report %s (%d failed)", *reportPath, report.Failed)
+	}
+}
+
+// extractionReport summarizes a run for tracking extraction coverage
+// across versions (-report).
+type extractionReport struct {
+	Version       string         `json:"version"`
+	Total         int            `json:"total"`
+	Succeeded     int            `json:"succeeded"`
+	Failed        int            `json:"failed"`
+	Reused        int            `json:"reused"`
+	Plugins       []pluginReport `json:"plugins"`       // sorted by plugin
+	MissingMixins []string       `json:"missingMixins"` // mixin URLs that returned 404
+	Options       optionCoverage `json:"options"`
+}
+
+// pluginReport is the extraction outcome of one plugin.
+type pluginReport struct {
+	Plugin  string `json:"plugin"` // e.g. "filter/grok"
+	Status  string `json:"status"` // "ok", "failed" or "reused"
+	Error   string `json:"error,omitempty"`
+	Options int    `json:"options"`
+}
+
+// optionCoverage counts documented options with and without each field.
+type optionCoverage struct {
+	Total       int      `json:"total"`
+	Type        coverage `json:"type"`
+	Default     coverage `json:"default"`
+	Description coverage `json:"description"`
+}
+
+type coverage struct {
+	With    int `json:"with"`
+	Without int `json:"without"`
+}
+
+func (c *coverage) add(has bool) {
+	if has {
+		c.With++
+	} else {
+		c.Without++
+	}
+}
+
+// finish fills in the totals and the option coverage of the written docs.
+func (r *extractionReport) finish(docMaps ...map[string]*PluginDoc) {
+	sort.Slice(r.Plugins, func(i, j int) bool { return r.Plugins[i].Plugin < r.Plugins[j].Plugin })
+	r.Total = len(r.Plugins)
+	for _, p := range r.Plugins {
+		switch p.Status {
+		case "ok":
+			r.Succeeded++
+		case "failed":
+			r.Failed++
+		case "reused":
+			r.Reused++
+		}
+	}
+
+	r.MissingMixins = missingMixinURLs()
+
+	for _, docs := range docMaps {
+		for _, doc := range docs {
+			for _, o := range doc.Options {
+				r.Options.Total++
+				r.Options.Type.add(o.Type != "")
+				r.Options.Default.add(o.Default != "")
+				r.Options.Description.add(o.Description != "")
+			}
+		}
+	}
+}
+
+func writeReport(path string, report *extractionReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// optionTypeRegex matches the option types extractOptionDocFromLine
+// produces: a validator name or enum, optionally as a list.
+var optionTypeRegex = regexp.MustCompile(`^(list of )?(\w+|string, one of(: \S.*)?)$`)
+
+// checkRegistry returns the sanity problems of an assembled registry, for
+// -strict: problems that point at an extraction bug rather than at
+// Logstash. Every section and the codecs must be non-empty without
+// duplicates, some plugin must have options, and option names and types
+// must be well-formed.
+func checkRegistry(d *RegistryData) []string {
+	var problems []string
+	checkNames := func(what string, names []string) {
+		if len(names) == 0 {
+			problems = append(problems, fmt.Sprintf("no %s", what))
+		}
+		seen := map[string]bool{}
+		for _, n := range names {
+			if seen[n] {
+				problems = append(problems, fmt.Sprintf("duplicate %s %q", what, n))
+			}
+			seen[n] = true
+		}
+	}
+	for _, typ := range []string{"input", "filter", "output"} {
+		checkNames(typ+" plugins", d.Plugins[typ])
+	}
+	checkNames("codecs", d.Codecs)
+
+	if len(d.PluginOptions) == 0 {
+		problems = append(problems, "no plugin has any options")
+	}
+	checkOption := func(where, name string, doc *OptionDoc) {
+		if name == "" || strings.ContainsAny(name, " \t\r\n") {
+			problems = append(problems, fmt.Sprintf("%s: malformed option name %q", where, name))
+		}
+		if doc != nil && doc.Type != "" && !optionTypeRegex.MatchString(doc.Type) {
+			problems = append(problems, fmt.Sprintf("%s: option %q has malformed type %q", where, name, doc.Type))
+		}
+	}
+	for _, key := range sortedKeys(d.PluginOptions) {
+		for _, name := range d.PluginOptions[key] {
+			checkOption(key, name, nil)
+		}
+	}
+	for _, docs := range []map[string]*PluginDoc{d.PluginDocs, d.CodecDocs} {
+		for _, key := range sortedKeys(docs) {
+			for _, name := range sortedKeys(docs[key].Options) {
+				checkOption(key, name, docs[key].Options[name])
+			}
+		}
+	}
+	return problems
+}
+
+// sortedKeys returns the keys of m in order, for deterministic output.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeGzip writes b gzip-compressed to path and returns the compressed size.
+func writeGzip(path string, b []byte) (int, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := zw.Write(b); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return buf.Len(), os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+var (
+	missingMixins   = map[string]bool{}
+	missingMixinsMu sync.Mutex
+)
+
+// recordMissingMixin notes a mixin source that returned 404.
+func recordMissingMixin(url string) {
+	missingMixinsMu.Lock()
+	missingMixins[url] = true
+	missingMixinsMu.Unlock()
+}
+
+// missingMixinURLs returns the recorded mixin 404s, sorted.
+func missingMixinURLs() []string {
+	missingMixinsMu.Lock()
+	defer missingMixinsMu.Unlock()
+	urls := make([]string, 0, len(missingMixins))
+	for url := range missingMixins {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// overridesJSON holds hand-maintained option metadata the scraper can't
+// infer from plugin sources, keyed by plugin ("filter/grok", or "codec/json")
+// and option name.
+//
+//go:embed overrides.json
+var overridesJSON []byte
+
+// optionOverride is the metadata overrides.json can set on an option.
+type optionOverride struct {
+	Conflicts []string `json:"conflicts"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	HashKeys  []string `json:"hashKeys"`
+}
+
+// applyOverrides merges overrides.json into the plugin, codec and common
+// option docs. Keys are "type/plugin", "codec/name" or "common/type".
+// Entries for plugins or options missing from this version are skipped.
+func applyOverrides(pluginDocs, codecDocs map[string]*PluginDoc, commonOptionDocs map[string]map[string]*OptionDoc) error {
+	var overrides map[string]map[string]optionOverride
+	dec := json.NewDecoder(bytes.NewReader(overridesJSON))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&overrides); err != nil {
+		return fmt.Errorf("overrides.json: %w", err)
+	}
+
+	for key, options := range overrides {
+		var docs map[string]*OptionDoc
+		if name, ok := strings.CutPrefix(key, "codec/"); ok {
+			if doc := codecDocs[name]; doc != nil {
+				docs = doc.Options
+			}
+		} else if typ, ok := strings.CutPrefix(key, "common/"); ok {
+			docs = commonOptionDocs[typ]
+		} else if doc := pluginDocs[key]; doc != nil {
+			docs = doc.Options
+		}
+		for name, o := range options {
+			od := docs[name]
+			if od == nil {
+				continue
+			}
+			if o.Conflicts != nil {
+				od.Conflicts = o.Conflicts
+			}
+			if o.Min != nil {
+				od.Min = o.Min
+			}
+			if o.Max != nil {
+				od.Max = o.Max
+			}
+			if o.HashKeys != nil {
+				od.HashKeys = o.HashKeys
+			}
+		}
+	}
+	return nil
+}
+
+// loadPreviousRegistry reads a registry JSON written by an earlier run.
+// Registries written before gem versions were recorded can't be reused.
+func loadPreviousRegistry(path string) (*RegistryData, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rd RegistryData
+	if err := json.Unmarshal(b, &rd); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(rd.PluginVersions) == 0 {
+		return nil, fmt.Errorf("%s has no pluginVersions; regenerate it once without -since", path)
+	}
+	return &rd, nil
 }
 
 // buildCommonOptionDocs returns hardcoded docs for base class options.
@@ -291,31 +725,72 @@ func buildCommonOptionDocs() map[string]map[string]*OptionDoc {
 	}
 }
 
+// extractResult is the outcome of extractRichOptions for one plugin.
+type extractResult struct {
+	options         []richOption
+	description     string
+	longDescription string // only with -full-descriptions
+	constraint      string // see PluginDoc.Constraint
+	err             error
+}
+
+// extractAll runs extractRichOptions for each gem on a pool of workers.
+// Results are returned in the order of gems, so the output doesn't depend on
+// scheduling.
+func extractAll(src sourceFetcher, gems []gemInfo, workers int) []extractResult {
+	results := make([]extractResult, len(gems))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				opts, desc, longDesc, err := extractRichOptions(src, gems[i])
+				results[i] = extractResult{options: opts, description: desc, longDescription: longDesc, err: err}
+				if err == nil {
+					results[i].constraint = logstashConstraint(src, gems[i])
+				}
+			}
+		}()
+	}
+	for i := range gems {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
 // extractRichOptions fetches a plugin's Ruby source and extracts config options with rich metadata.
-// Returns the options, plugin description, and any error.
-func extractRichOptions(g gemInfo) ([]richOption, string, error) {
+// Returns the options, plugin description, long description (empty unless
+// -full-descriptions), and any error.
+func extractRichOptions(src sourceFetcher, g gemInfo) ([]richOption, string, string, error) {
 	typePlural := g.typ + "s"
-	url := fmt.Sprintf("https://raw.githubusercontent.com/logstash-plugins/%s/v%s/lib/logstash/%s/%s.rb",
-		g.repo, g.version, typePlural, g.name)
-
-	body, err := fetchRaw(url)
+	body, err := src.pluginFile(g.repo, g.version, "lib/logstash/"+typePlural+"/"+g.name+".rb")
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
 
 	source := string(body)
 	pluginDesc := extractPluginDescription(source)
-	opts := parseRichConfigOptions(source)
+	var longDesc string
+	if fullDescriptions {
+		longDesc = extractPluginLongDescription(source)
+	}
+	opts := withSource(parseRichConfigOptions(source), "plugin")
 
 	// Extract mixin options by following require statements (API-free)
-	mixinOpts := extractMixinRichOptions(g, source)
+	mixinOpts := extractMixinRichOptions(src, g, source)
 	opts = append(opts, mixinOpts...)
 
 	// Fallback: try tree API for additional mixins
-	treeOpts := extractMixinRichOptionsFromTree(g)
+	treeOpts := extractMixinRichOptionsFromTree(src, g)
 	opts = append(opts, treeOpts...)
 
-	// Deduplicate (keep first occurrence which has the primary source's data)
+	// Deduplicate, keeping the first occurrence: the plugin's own source is
+	// parsed first, then required mixins, then the tree fallback, so that is
+	// the most specific declaration.
 	seen := map[string]bool{}
 	var unique []richOption
 	for _, o := range opts {
@@ -324,11 +799,202 @@ func extractRichOptions(g gemInfo) ([]richOption, string, error) {
 			unique = append(unique, o)
 		}
 	}
-	return unique, pluginDesc, nil
+
+	if docsFallback {
+		fillDescriptionsFromDocs(src, g, unique)
+	}
+	return unique, pluginDesc, longDesc, nil
+}
+
+// fillDescriptionsFromDocs sets the description of options that have none
+// from the plugin's AsciiDoc docs page. A missing page is not an error; the
+// options just stay undocumented.
+func fillDescriptionsFromDocs(src sourceFetcher, g gemInfo, opts []richOption) {
+	missing := false
+	for _, o := range opts {
+		if o.Doc.Description == "" {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return
+	}
+
+	// Integration gems keep one page per plugin; standalone gems use index.
+	page := "index"
+	if g.repo != "logstash-"+g.typ+"-"+g.name {
+		page = g.typ + "-" + g.name
+	}
+	body, err := src.pluginFile(g.repo, g.version, "docs/"+page+".asciidoc")
+	if err != nil {
+		if !isNotFound(err) {
+			log.Printf("WARNING: failed to fetch docs for %s/%s: %v", g.typ, g.name, err)
+		}
+		return
+	}
+
+	descs := parseAsciidocOptionDescriptions(string(body))
+	for i := range opts {
+		if opts[i].Doc.Description == "" {
+			opts[i].Doc.Description = descs[opts[i].Name]
+		}
+	}
+}
+
+// parseAsciidocOptionDescriptions maps option names to the first paragraph
+// of their section in a plugin docs page. Sections look like:
+//
+//	[id="plugins-{type}s-{plugin}-hosts"]
+//	===== `hosts`
+//
+//	  * Value type is <<uri,uri>>
+//	  * Default value is `[//127.0.0.1]`
+//
+//	Sets the host(s) of the remote instance.
+func parseAsciidocOptionDescriptions(source string) map[string]string {
+	descs := map[string]string{}
+	lines := strings.Split(source, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := asciidocOptionIDRegex.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		name := m[1]
+
+		// Skip the heading and the value-type bullet list (including
+		// wrapped bullet lines), then take the first paragraph.
+		var para []string
+		inBullets := false
+		for j := i + 1; j < len(lines); j++ {
+			line := strings.TrimSpace(lines[j])
+			if asciidocOptionIDRegex.MatchString(line) {
+				break
+			}
+			if strings.HasPrefix(line, "=") {
+				continue
+			}
+			if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "** ") {
+				inBullets = true
+				continue
+			}
+			if line == "" {
+				if len(para) > 0 {
+					break
+				}
+				inBullets = false
+				continue
+			}
+			if inBullets {
+				continue
+			}
+			if strings.HasPrefix(line, "[source") || strings.HasPrefix(line, "----") ||
+				strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+				break
+			}
+			para = append(para, line)
+		}
+		if desc := cleanAsciidoc(strings.Join(para, " ")); desc != "" {
+			descs[name] = desc
+		}
+	}
+	return descs
+}
+
+// cleanAsciidoc reduces AsciiDoc links and cross references to their text:
+// https://url[text] -> text, <<anchor,text>> -> text, <<...-option>> -> option.
+func cleanAsciidoc(s string) string {
+	s = asciidocLinkRegex.ReplaceAllString(s, "$1")
+	s = asciidocXrefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		m := asciidocXrefRegex.FindStringSubmatch(ref)
+		if text := strings.TrimSpace(m[2]); text != "" {
+			return text
+		}
+		anchor := m[1]
+		return anchor[strings.LastIndex(anchor, "-")+1:]
+	})
+	return strings.TrimSpace(s)
 }
 
 // extractPluginDescription extracts the description comment block before the class declaration.
 func extractPluginDescription(source string) string {
+	commentLines := classComment(source)
+
+	// Extract just the first paragraph as the short description
+	// Stop at first blank line, AsciiDoc section header (====), or code block marker
+	var desc []string
+	for _, line := range commentLines {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "====") || strings.HasPrefix(line, "[source") ||
+			strings.HasPrefix(line, "---") || strings.HasPrefix(line, "NOTE:") ||
+			strings.HasPrefix(line, ".") && len(line) > 1 && line[1] != ' ' {
+			break
+		}
+		desc = append(desc, line)
+	}
+
+	result := strings.Join(desc, " ")
+	// Clean up AsciiDoc link syntax: https://url[text] -> text
+	result = asciidocLinkRegex.ReplaceAllString(result, "$1")
+	return strings.TrimSpace(result)
+}
+
+// extractPluginLongDescription returns the whole class comment as plain
+// paragraphs separated by blank lines. Source and literal blocks are
+// dropped, section headers and block titles become their own paragraph,
+// list items their own line, and links are reduced to their text.
+func extractPluginLongDescription(source string) string {
+	var paras []string
+	var para []string // lines of the current paragraph
+	flush := func() {
+		if text := cleanAsciidoc(strings.Join(para, "\n")); text != "" {
+			paras = append(paras, text)
+		}
+		para = nil
+	}
+
+	delimiter := "" // closing delimiter while inside a ---- or .... block
+	for _, line := range classComment(source) {
+		line = strings.TrimSpace(line)
+		if delimiter != "" {
+			if line == delimiter {
+				delimiter = ""
+			}
+			continue
+		}
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "----") || strings.HasPrefix(line, "...."):
+			flush()
+			delimiter = line
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			// Block attributes like [source,ruby] or [id="..."]
+			flush()
+		case strings.HasPrefix(line, "="):
+			flush()
+			para = append(para, strings.TrimSpace(strings.TrimLeft(line, "=")))
+			flush()
+		case strings.HasPrefix(line, ".") && len(line) > 1 && line[1] != ' ' && line[1] != '.':
+			flush()
+			para = append(para, line[1:])
+			flush()
+		case strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- ") || len(para) == 0:
+			para = append(para, line)
+		default:
+			para[len(para)-1] += " " + line
+		}
+	}
+	flush()
+	return strings.Join(paras, "\n\n")
+}
+
+// classComment returns the comment block immediately preceding the class
+// declaration, with the leading # stripped. Blank lines inside the block
+// are kept as "".
+func classComment(source string) []string {
 	lines := strings.Split(source, "\n")
 	classLine := -1
 	for i, line := range lines {
@@ -338,7 +1004,7 @@ func extractPluginDescription(source string) string {
 		}
 	}
 	if classLine < 0 {
-		return ""
+		return nil
 	}
 
 	// Collect comment block immediately preceding the class line
@@ -364,35 +1030,11 @@ func extractPluginDescription(source string) string {
 		commentLines = append(commentLines, text)
 	}
 
-	if len(commentLines) == 0 {
-		return ""
-	}
-
 	// Reverse (we collected bottom-up)
 	for i, j := 0, len(commentLines)-1; i < j; i, j = i+1, j-1 {
 		commentLines[i], commentLines[j] = commentLines[j], commentLines[i]
 	}
-
-	// Extract just the first paragraph as the short description
-	// Stop at first blank line, AsciiDoc section header (====), or code block marker
-	var desc []string
-	for _, line := range commentLines {
-		if line == "" {
-			break
-		}
-		if strings.HasPrefix(line, "====") || strings.HasPrefix(line, "[source") ||
-			strings.HasPrefix(line, "---") || strings.HasPrefix(line, "NOTE:") ||
-			strings.HasPrefix(line, ".") && len(line) > 1 && line[1] != ' ' {
-			break
-		}
-		desc = append(desc, line)
-	}
-
-	result := strings.Join(desc, " ")
-	// Clean up AsciiDoc link syntax: https://url[text] -> text
-	asciidocLinkRegex := regexp.MustCompile(`https?://[^\[]+\[([^\]]+)\]`)
-	result = asciidocLinkRegex.ReplaceAllString(result, "$1")
-	return strings.TrimSpace(result)
+	return commentLines
 }
 
 // parseRichConfigOptions extracts config options with rich metadata from Ruby source.
@@ -441,20 +1083,25 @@ func parseRichConfigOptions(source string) []richOption {
 			continue
 		}
 
-		// Join continuation lines (config that spans multiple lines)
+		// Join continuation lines (config that spans multiple lines): the
+		// next :key after a trailing comma, or anything inside a string,
+		// hash or array value left open, e.g. a multi-line :default => { ... }
+		// or :deprecated note.
 		fullLine := line
-		for j := i + 1; j < len(lines) && !strings.HasSuffix(strings.TrimSpace(fullLine), ",") == false; j++ {
+		for j := i + 1; j < len(lines); j++ {
+			open := valueOpen(fullLine)
+			if !open && !strings.HasSuffix(strings.TrimSpace(fullLine), ",") {
+				break
+			}
 			nextTrimmed := strings.TrimSpace(lines[j])
 			if nextTrimmed == "" || strings.HasPrefix(nextTrimmed, "#") || configRegex.MatchString(lines[j]) {
 				break
 			}
-			// Check if it looks like a continuation (starts with : or whitespace-heavy)
-			if strings.HasPrefix(nextTrimmed, ":") || strings.HasPrefix(nextTrimmed, "#") {
-				fullLine += " " + nextTrimmed
-				i = j
-			} else {
+			if !open && !strings.HasPrefix(nextTrimmed, ":") {
 				break
 			}
+			fullLine += " " + nextTrimmed
+			i = j
 		}
 
 		// Skip obsolete options
@@ -474,6 +1121,30 @@ func parseRichConfigOptions(source string) []richOption {
 	return opts
 }
 
+// valueOpen reports whether s ends inside a quoted string or leaves a { or
+// [ open; brackets inside strings don't count.
+func valueOpen(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			depth--
+		}
+	}
+	return quote != 0 || depth > 0
+}
+
 // findConfigLineIndex walks backward from line i to find the actual config line
 // (skipping any continuation lines we may have joined).
 func findConfigLineIndex(lines []string, i int) int {
@@ -554,7 +1225,9 @@ func extractOptionDocFromLine(line string) OptionDoc {
 
 	// Deprecated
 	if m := deprecatedRegex.FindStringSubmatch(line); m != nil {
-		doc.Deprecated = m[1]
+		// One of the two quote styles matched; a note continued on the
+		// next lines was joined with blanks (see parseRichConfigOptions).
+		doc.Deprecated = rubyEscapeRegex.ReplaceAllString(m[1]+m[2], "$1")
 	}
 
 	return doc
@@ -615,7 +1288,7 @@ func extractPrecedingComment(lines []string, i int) string {
 }
 
 // extractMixinRichOptions extracts rich options from mixin files.
-func extractMixinRichOptions(g gemInfo, source string) []richOption {
+func extractMixinRichOptions(src sourceFetcher, g gemInfo, source string) []richOption {
 	matches := requireMixinRegex.FindAllStringSubmatch(source, -1)
 	if len(matches) == 0 {
 		return nil
@@ -631,15 +1304,16 @@ func extractMixinRichOptions(g gemInfo, source string) []richOption {
 		}
 		fetched[rbPath] = true
 
-		rawURL := fmt.Sprintf("https://raw.githubusercontent.com/logstash-plugins/%s/v%s/%s",
-			g.repo, g.version, rbPath)
-		rb, err := fetchRaw(rawURL)
+		rb, err := fetchMixin(src, g, rbPath)
 		if err != nil {
+			if isNotFound(err) {
+				recordMissingMixin(src.location(g.repo, g.version, rbPath))
+			}
 			continue
 		}
 
 		mixinSource := string(rb)
-		allOpts = append(allOpts, parseRichConfigOptions(mixinSource)...)
+		allOpts = append(allOpts, withSource(parseRichConfigOptions(mixinSource), mixinSourceName(rbPath))...)
 
 		for _, sub := range requireMixinRegex.FindAllStringSubmatch(mixinSource, -1) {
 			subPath := "lib/logstash/plugin_mixins/" + sub[1] + ".rb"
@@ -648,21 +1322,66 @@ func extractMixinRichOptions(g gemInfo, source string) []richOption {
 			}
 			fetched[subPath] = true
 
-			subURL := fmt.Sprintf("https://raw.githubusercontent.com/logstash-plugins/%s/v%s/%s",
-				g.repo, g.version, subPath)
-			subRb, err := fetchRaw(subURL)
+			subRb, err := fetchMixin(src, g, subPath)
 			if err != nil {
+				if isNotFound(err) {
+					recordMissingMixin(src.location(g.repo, g.version, subPath))
+				}
 				continue
 			}
-			allOpts = append(allOpts, parseRichConfigOptions(string(subRb))...)
+			allOpts = append(allOpts, withSource(parseRichConfigOptions(string(subRb)), mixinSourceName(subPath))...)
 		}
 	}
 	return allOpts
 }
 
-// extractMixinRichOptionsFromTree uses the tree API as a fallback.
-func extractMixinRichOptionsFromTree(g gemInfo) []richOption {
-	tree, err := getRepoTree(g.repo, g.version)
+var (
+	mixinFiles   = map[string]*mixinFetch{}
+	mixinFilesMu sync.Mutex
+)
+
+// mixinFetch is one mixin file read, shared by every plugin that requires it.
+type mixinFetch struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// fetchMixin reads a mixin file of g's repository once per run; plugins of
+// the same repository (integration plugins) share the result, failures
+// included. Each repository ships its own copy of a mixin, so files are
+// keyed by repo, version and path.
+func fetchMixin(src sourceFetcher, g gemInfo, path string) ([]byte, error) {
+	key := g.repo + "@" + g.version + ":" + path
+	mixinFilesMu.Lock()
+	f, ok := mixinFiles[key]
+	if !ok {
+		f = &mixinFetch{}
+		mixinFiles[key] = f
+	}
+	mixinFilesMu.Unlock()
+
+	f.once.Do(func() { f.data, f.err = src.pluginFile(g.repo, g.version, path) })
+	return f.data, f.err
+}
+
+// withSource sets the Source of each option to source.
+func withSource(opts []richOption, source string) []richOption {
+	for i := range opts {
+		opts[i].Doc.Source = source
+	}
+	return opts
+}
+
+// mixinSourceName names a mixin by its file, e.g.
+// "lib/logstash/plugin_mixins/http_client.rb" -> "mixin:plugin_mixins/http_client".
+func mixinSourceName(path string) string {
+	return "mixin:" + strings.TrimSuffix(strings.TrimPrefix(path, "lib/logstash/"), ".rb")
+}
+
+// extractMixinRichOptionsFromTree uses the repo tree as a fallback.
+func extractMixinRichOptionsFromTree(src sourceFetcher, g gemInfo) []richOption {
+	tree, err := src.repoTree(g.repo, g.version)
 	if err != nil {
 		return nil
 	}
@@ -677,19 +1396,17 @@ func extractMixinRichOptionsFromTree(g gemInfo) []richOption {
 			continue
 		}
 
-		rawURL := fmt.Sprintf("https://raw.githubusercontent.com/logstash-plugins/%s/v%s/%s",
-			g.repo, g.version, entry.Path)
-		rb, err := fetchRaw(rawURL)
+		rb, err := fetchMixin(src, g, entry.Path)
 		if err != nil {
 			continue
 		}
-		allOpts = append(allOpts, parseRichConfigOptions(string(rb))...)
+		allOpts = append(allOpts, withSource(parseRichConfigOptions(string(rb)), mixinSourceName(entry.Path))...)
 	}
 	return allOpts
 }
 
 // fetchGems fetches the Gemfile lockfile and parses gem entries.
-func fetchGems(version string) ([]gemInfo, error) {
+func fetchGems(src sourceFetcher, version string) ([]gemInfo, error) {
 	lockfileNames := []string{
 		"Gemfile.jruby-3.1.lock.release",
 		"Gemfile.jruby-3.4.lock.release",
@@ -707,8 +1424,7 @@ func fetchGems(version string) ([]gemInfo, error) {
 	var lastErr error
 	for _, ver := range versions {
 		for _, name := range lockfileNames {
-			url := fmt.Sprintf("https://raw.githubusercontent.com/elastic/logstash/v%s/%s", ver, name)
-			b, err := fetchRaw(url)
+			b, err := src.lockfile(ver, name)
 			if err != nil {
 				lastErr = err
 				continue
@@ -741,16 +1457,11 @@ func fetchGems(version string) ([]gemInfo, error) {
 	return gems, nil
 }
 
-// getRepoTree fetches the full recursive file tree for a repo at a given tag.
-// Uses a single GitHub API call and caches the result.
-func getRepoTree(repo, version string) ([]treeEntry, error) {
-	cacheKey := repo + "@" + version
-	if cached, ok := treeCache[cacheKey]; ok {
-		return cached, nil
-	}
-
+// getRepoTree fetches the full recursive file tree for a repo at a given tag
+// with a single GitHub API call.
+func getRepoTree(f Fetcher, repo, version string) ([]treeEntry, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/logstash-plugins/%s/git/trees/v%s?recursive=1", repo, version)
-	body, err := fetchAPI(url)
+	body, err := f.API(url)
 	if err != nil {
 		return nil, err
 	}
@@ -761,8 +1472,6 @@ func getRepoTree(repo, version string) ([]treeEntry, error) {
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, err
 	}
-
-	treeCache[cacheKey] = resp.Tree
 	return resp.Tree, nil
 }
 
@@ -779,21 +1488,19 @@ var requireMixinRegex = regexp.MustCompile(`require\s+['"]logstash/plugin_mixins
 // resolveIntegration finds sub-plugins within an integration gem.
 // First tries the gemspec (API-free via raw.githubusercontent.com),
 // then falls back to the tree API.
-func resolveIntegration(ig gemInfo) ([]gemInfo, error) {
-	subs, err := resolveIntegrationFromGemspec(ig)
+func resolveIntegration(src sourceFetcher, ig gemInfo) ([]gemInfo, error) {
+	subs, err := resolveIntegrationFromGemspec(src, ig)
 	if err == nil && len(subs) > 0 {
 		return subs, nil
 	}
 
-	return resolveIntegrationFromTree(ig)
+	return resolveIntegrationFromTree(src, ig)
 }
 
 // resolveIntegrationFromGemspec parses the gemspec's integration_plugins metadata.
 // Handles both quoted string and %w() array formats.
-func resolveIntegrationFromGemspec(ig gemInfo) ([]gemInfo, error) {
-	url := fmt.Sprintf("https://raw.githubusercontent.com/logstash-plugins/%s/v%s/%s.gemspec",
-		ig.repo, ig.version, ig.repo)
-	body, err := fetchRaw(url)
+func resolveIntegrationFromGemspec(src sourceFetcher, ig gemInfo) ([]gemInfo, error) {
+	body, err := src.pluginFile(ig.repo, ig.version, ig.repo+".gemspec")
 	if err != nil {
 		return nil, err
 	}
@@ -843,9 +1550,37 @@ func resolveIntegrationFromGemspec(ig gemInfo) ([]gemInfo, error) {
 	return subs, nil
 }
 
-// resolveIntegrationFromTree uses the tree API to find sub-plugins.
-func resolveIntegrationFromTree(ig gemInfo) ([]gemInfo, error) {
-	tree, err := getRepoTree(ig.repo, ig.version)
+// logstashCoreDependencyRegex matches a gemspec dependency on logstash-core,
+// capturing its requirement strings: s.add_runtime_dependency "logstash-core", ">= 8.10.0".
+var logstashCoreDependencyRegex = regexp.MustCompile(`add_(?:runtime_)?dependency\s*\(?\s*['"]logstash-core['"]((?:\s*,\s*['"][^'"]*['"])+)`)
+
+var quotedRequirementRegex = regexp.MustCompile(`['"]([^'"]*)['"]`)
+
+// logstashConstraint returns the Logstash version requirement a plugin's
+// gemspec declares through its logstash-core dependency, e.g. ">= 8.10.0" or
+// ">= 6.5.0, < 9", or "" when it declares none. Integration plugins share
+// the integration's gemspec. A missing gemspec is not an error.
+func logstashConstraint(src sourceFetcher, g gemInfo) string {
+	body, err := src.pluginFile(g.repo, g.version, g.repo+".gemspec")
+	if err != nil {
+		return ""
+	}
+	m := logstashCoreDependencyRegex.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	var reqs []string
+	for _, q := range quotedRequirementRegex.FindAllSubmatch(m[1], -1) {
+		if r := strings.TrimSpace(string(q[1])); r != "" {
+			reqs = append(reqs, r)
+		}
+	}
+	return strings.Join(reqs, ", ")
+}
+
+// resolveIntegrationFromTree uses the repo tree to find sub-plugins.
+func resolveIntegrationFromTree(src sourceFetcher, ig gemInfo) ([]gemInfo, error) {
+	tree, err := src.repoTree(ig.repo, ig.version)
 	if err != nil {
 		return nil, err
 	}
@@ -896,61 +1631,108 @@ func shouldSkipFile(stem string) bool {
 	return false
 }
 
-// fetchRaw fetches from raw.githubusercontent.com (no API rate limit).
-func fetchRaw(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	if token != "" {
-		req.Header.Set("Authorization", "token "+token)
+// cachePath returns the cache file for a URL. 404s are stored as an empty
+// file with a .404 suffix.
+func cachePath(url string, notFound bool) string {
+	sum := sha256.Sum256([]byte(url))
+	name := hex.EncodeToString(sum[:])
+	if notFound {
+		name += ".404"
 	}
+	return filepath.Join(cacheDir, name)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+// cacheFresh reports whether path exists and is younger than cacheTTL.
+func cacheFresh(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) < cacheTTL
+}
+
+// cachedFetch serves url from the disk cache when -cache is set, and
+// otherwise calls fetch (see fetchWithRetry) and stores its body, or the
+// fact that it was a 404.
+func cachedFetch(url string, fetch func(string) ([]byte, error)) ([]byte, error) {
+	if cacheDir == "" {
+		return fetchWithRetry(url, fetch)
+	}
+	if path := cachePath(url, false); cacheFresh(path) {
+		return os.ReadFile(path)
+	}
+	if cacheFresh(cachePath(url, true)) {
+		return nil, &httpStatusError{code: 404, url: url}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP %d for %s", resp.StatusCode, url)
+	body, err := fetchWithRetry(url, fetch)
+	switch {
+	case err == nil:
+		if werr := writeCacheFile(cachePath(url, false), body); werr != nil {
+			log.Printf("WARNING: failed to cache %s: %v", url, werr)
+		}
+	case isNotFound(err):
+		if werr := writeCacheFile(cachePath(url, true), nil); werr != nil {
+			log.Printf("WARNING: failed to cache %s: %v", url, werr)
+		}
 	}
-	return io.ReadAll(resp.Body)
+	return body, err
 }
 
-// fetchAPI fetches from the GitHub API with rate limiting.
-func fetchAPI(url string) ([]byte, error) {
-	since := time.Since(lastAPICall)
-	if since < apiDelay {
-		time.Sleep(apiDelay - since)
-	}
+// fetchWithRetry calls fetch up to fetchAttempts times, doubling the delay
+// between attempts, when it fails with a 5xx or network error. Other errors,
+// such as a 404, are returned at once. On a rate limit that resets within
+// maxRateLimitWait it sleeps until the reset instead.
+func fetchWithRetry(url string, fetch func(string) ([]byte, error)) ([]byte, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		body, err := fetch(url)
+		if err == nil || attempt == fetchAttempts {
+			return body, err
+		}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+		var rl *rateLimitError
+		switch {
+		case errors.As(err, &rl):
+			wait := time.Until(rl.reset)
+			if rl.reset.IsZero() || wait > maxRateLimitWait {
+				return nil, err
+			}
+			log.Printf("Rate limited, waiting %s for the reset", wait.Round(time.Second))
+			time.Sleep(wait + time.Second)
+		case isTransient(err):
+			log.Printf("Retrying %s in %s: %v", url, delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		default:
+			return nil, err
+		}
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	if token != "" {
-		req.Header.Set("Authorization", "token "+token)
+}
+
+// isTransient reports whether a fetch error is worth retrying: a 5xx
+// response, or a failure to connect or read the response.
+func isTransient(err error) bool {
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.code >= 500
 	}
+	var ue *neturl.Error
+	return errors.As(err, &ue) || errors.Is(err, io.ErrUnexpectedEOF)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	lastAPICall = time.Now()
+// writeCacheFile writes through a temporary file so concurrent workers never
+// read a partially written entry.
+func writeCacheFile(path string, data []byte) error {
+	f, err := os.CreateTemp(cacheDir, ".tmp-*")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 403 {
-		body, _ := io.ReadAll(resp.Body)
-		if strings.Contains(string(body), "rate limit") {
-			return nil, fmt.Errorf("GitHub API rate limit exceeded. Set GITHUB_TOKEN env var or use -token flag")
-		}
-		return nil, fmt.Errorf("HTTP 403 for %s: %s", url, string(body))
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
 	}
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP %d for %s", resp.StatusCode, url)
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
 	}
-	return io.ReadAll(resp.Body)
+	return os.Rename(f.Name(), path)
 }
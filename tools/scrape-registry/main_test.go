@@ -0,0 +1,264 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFetcher is a Fetcher serving canned bodies by URL and counting the
+// requests; unknown URLs are 404s.
+type fakeFetcher struct {
+	files map[string]string
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newFakeFetcher(files map[string]string) *fakeFetcher {
+	return &fakeFetcher{files: files, calls: map[string]int{}}
+}
+
+func (f *fakeFetcher) get(url string) ([]byte, error) {
+	f.mu.Lock()
+	f.calls[url]++
+	f.mu.Unlock()
+	body, ok := f.files[url]
+	if !ok {
+		return nil, &httpStatusError{code: 404, url: url}
+	}
+	return []byte(body), nil
+}
+
+func (f *fakeFetcher) Raw(url string) ([]byte, error) { return f.get(url) }
+func (f *fakeFetcher) API(url string) ([]byte, error) { return f.get(url) }
+
+func (f *fakeFetcher) count(url string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[url]
+}
+
+func TestParseRichConfigOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []richOption
+	}{
+		{
+			name:   "required",
+			source: "  config :host, :validate => :string, :required => true\n",
+			want:   []richOption{{Name: "host", Doc: OptionDoc{Type: "string", Required: true}}},
+		},
+		{
+			name:   "default with description",
+			source: "  # The port to listen on.\n  config :port, :validate => :number, :default => 5044\n",
+			want:   []richOption{{Name: "port", Doc: OptionDoc{Type: "number", Default: "5044", Description: "The port to listen on."}}},
+		},
+		{
+			name:   "quoted default",
+			source: "  config :index, :validate => :string, :default => \"logstash-%{+YYYY}\"\n",
+			want:   []richOption{{Name: "index", Doc: OptionDoc{Type: "string", Default: "logstash-%{+YYYY}"}}},
+		},
+		{
+			name:   "deprecated",
+			source: "  config :user, :validate => :string, :deprecated => \"Use `username` instead.\"\n",
+			want:   []richOption{{Name: "user", Doc: OptionDoc{Type: "string", Deprecated: "Use `username` instead."}}},
+		},
+		{
+			name:   "deprecated note quoting with the other quote",
+			source: "  config :ssl, :validate => :boolean, :deprecated => \"Set 'ssl_enabled' instead.\"\n",
+			want:   []richOption{{Name: "ssl", Doc: OptionDoc{Type: "boolean", Deprecated: "Set 'ssl_enabled' instead."}}},
+		},
+		{
+			name:   "deprecated note with escaped quotes",
+			source: "  config :cacert, :validate => :path, :deprecated => \"Set \\\"ssl_certificate_authorities\\\" instead.\"\n",
+			want:   []richOption{{Name: "cacert", Doc: OptionDoc{Type: "path", Deprecated: `Set "ssl_certificate_authorities" instead.`}}},
+		},
+		{
+			name: "multi-line deprecated note",
+			source: "  config :verify_mode, :validate => :string,\n" +
+				"    :deprecated => \"Set 'ssl_client_authentication'\n" +
+				"    instead.\"\n" +
+				"  config :port, :validate => :number\n",
+			want: []richOption{
+				{Name: "verify_mode", Doc: OptionDoc{Type: "string", Deprecated: "Set 'ssl_client_authentication' instead."}},
+				{Name: "port", Doc: OptionDoc{Type: "number"}},
+			},
+		},
+		{
+			name:   "obsolete options are skipped",
+			source: "  config :old, :validate => :string, :obsolete => \"Removed.\"\n  config :new, :validate => :string\n",
+			want:   []richOption{{Name: "new", Doc: OptionDoc{Type: "string"}}},
+		},
+		{
+			name:   "validate array",
+			source: "  config :mode, :validate => [\"server\", \"client\"], :default => \"client\"\n",
+			want:   []richOption{{Name: "mode", Doc: OptionDoc{Type: "string, one of: server, client", Default: "client"}}},
+		},
+		{
+			name:   "validate %w array",
+			source: "  config :proto, :validate => %w(tcp udp)\n",
+			want:   []richOption{{Name: "proto", Doc: OptionDoc{Type: "string, one of: tcp, udp"}}},
+		},
+		{
+			name:   "list",
+			source: "  config :hosts, :validate => :uri, :list => true\n",
+			want:   []richOption{{Name: "hosts", Doc: OptionDoc{Type: "list of uri"}}},
+		},
+		{
+			name:   "continuation lines",
+			source: "  config :match, :validate => :hash,\n    :default => {},\n    :required => true\n",
+			want:   []richOption{{Name: "match", Doc: OptionDoc{Type: "hash", Default: "{}", Required: true}}},
+		},
+		{
+			name: "multi-line hash default",
+			source: "  config :headers, :validate => :hash, :default => {\n" +
+				"    \"a\" => 1,\n" +
+				"    \"b\" => 2\n" +
+				"  }\n" +
+				"  config :enabled, :validate => :boolean\n",
+			want: []richOption{
+				{Name: "headers", Doc: OptionDoc{Type: "hash", Default: `{ "a" => 1, "b" => 2 }`}},
+				{Name: "enabled", Doc: OptionDoc{Type: "boolean"}},
+			},
+		},
+		{
+			name: "CONFIG_PARAMS hash",
+			source: "  CONFIG_PARAMS = {\n" +
+				"    # Hosts to connect to\n" +
+				"    :hosts => { :validate => :uri, :list => true },\n" +
+				"    :user => { :validate => :string, :required => true }\n" +
+				"  }\n",
+			want: []richOption{
+				{Name: "hosts", Doc: OptionDoc{Type: "list of uri", Description: "Hosts to connect to"}},
+				{Name: "user", Doc: OptionDoc{Type: "string", Required: true}},
+			},
+		},
+		{
+			name:   "config_name is not an option",
+			source: "  config_name \"beats\"\n  config :port, :validate => :number, :required => true\n",
+			want:   []richOption{{Name: "port", Doc: OptionDoc{Type: "number", Required: true}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRichConfigOptions(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRichOptions(t *testing.T) {
+	const base = "https://raw.githubusercontent.com/logstash-plugins/logstash-input-test/v1.0.0/"
+	net := newFakeFetcher(map[string]string{
+		base + "lib/logstash/inputs/test.rb": "require 'logstash/plugin_mixins/shared'\n" +
+			"class LogStash::Inputs::Test < LogStash::Inputs::Base\n" +
+			"  config_name \"test\"\n" +
+			"  # The port.\n" +
+			"  config :port, :validate => :number, :required => true\n" +
+			"end\n",
+		base + "lib/logstash/plugin_mixins/shared.rb": "  config :port, :validate => :string\n" +
+			"  config :timeout, :validate => :number, :default => 10\n",
+		"https://api.github.com/repos/logstash-plugins/logstash-input-test/git/trees/v1.0.0?recursive=1": `{"tree": [{"path": "lib/logstash/plugin_mixins/shared.rb", "type": "blob"}]}`,
+	})
+	g := gemInfo{repo: "logstash-input-test", typ: "input", name: "test", version: "1.0.0"}
+
+	opts, _, _, err := extractRichOptions(newGithubFetcher(net), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []richOption{
+		{Name: "port", Doc: OptionDoc{Type: "number", Required: true, Description: "The port.", Source: "plugin"}},
+		{Name: "timeout", Doc: OptionDoc{Type: "number", Default: "10", Source: "mixin:plugin_mixins/shared"}},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("got  %+v\nwant %+v", opts, want)
+	}
+	// The required mixin and the tree fallback share one read of the file.
+	if n := net.count(base + "lib/logstash/plugin_mixins/shared.rb"); n != 1 {
+		t.Errorf("mixin fetched %d times, want 1", n)
+	}
+}
+
+func TestRepoTreeFetchedOnce(t *testing.T) {
+	const url = "https://api.github.com/repos/logstash-plugins/logstash-codec-x/git/trees/v2.0.0?recursive=1"
+	net := newFakeFetcher(map[string]string{url: `{"tree": [{"path": "lib", "type": "tree"}]}`})
+	src := newGithubFetcher(net)
+	for range 2 {
+		tree, err := src.repoTree("logstash-codec-x", "2.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []treeEntry{{Path: "lib", Type: "tree"}}; !reflect.DeepEqual(tree, want) {
+			t.Errorf("tree = %+v, want %+v", tree, want)
+		}
+	}
+	if n := net.count(url); n != 1 {
+		t.Errorf("tree fetched %d times, want 1", n)
+	}
+}
+
+func TestCachedFetch(t *testing.T) {
+	oldDir, oldTTL := cacheDir, cacheTTL
+	cacheDir, cacheTTL = t.TempDir(), time.Hour
+	t.Cleanup(func() { cacheDir, cacheTTL = oldDir, oldTTL })
+
+	const found, missing = "https://example.test/found", "https://example.test/missing"
+	net := newFakeFetcher(map[string]string{found: "body"})
+
+	for range 2 {
+		body, err := cachedFetch(found, net.Raw)
+		if err != nil || string(body) != "body" {
+			t.Errorf("cachedFetch(found) = %q, %v", body, err)
+		}
+		if _, err := cachedFetch(missing, net.Raw); !isNotFound(err) {
+			t.Errorf("cachedFetch(missing) error = %v, want a 404", err)
+		}
+	}
+	// The second round is served from the cache, 404 included.
+	if n := net.count(found); n != 1 {
+		t.Errorf("found fetched %d times, want 1", n)
+	}
+	if n := net.count(missing); n != 1 {
+		t.Errorf("missing fetched %d times, want 1", n)
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	old := overridesJSON
+	t.Cleanup(func() { overridesJSON = old })
+	overridesJSON = []byte(`{
+		"input/tcp": {"port": {"min": 1, "max": 65535}, "gone": {"min": 1}},
+		"input/missing": {"port": {"min": 1}},
+		"codec/json": {"charset": {"conflicts": ["x"]}},
+		"common/output": {"workers": {"min": 1}}
+	}`)
+	port := &OptionDoc{Type: "number"}
+	charset := &OptionDoc{Type: "string"}
+	workers := &OptionDoc{Type: "number"}
+	pluginDocs := map[string]*PluginDoc{"input/tcp": {Options: map[string]*OptionDoc{"port": port}}}
+	codecDocs := map[string]*PluginDoc{"json": {Options: map[string]*OptionDoc{"charset": charset}}}
+	common := map[string]map[string]*OptionDoc{"output": {"workers": workers}}
+
+	if err := applyOverrides(pluginDocs, codecDocs, common); err != nil {
+		t.Fatal(err)
+	}
+	if port.Min == nil || *port.Min != 1 || port.Max == nil || *port.Max != 65535 {
+		t.Errorf("port bounds = %v, %v", port.Min, port.Max)
+	}
+	if workers.Min == nil || *workers.Min != 1 || workers.Max != nil {
+		t.Errorf("workers bounds = %v, %v", workers.Min, workers.Max)
+	}
+	if !reflect.DeepEqual(charset.Conflicts, []string{"x"}) {
+		t.Errorf("charset conflicts = %v", charset.Conflicts)
+	}
+
+	overridesJSON = []byte(`{"input/tcp": {"port": {"minimum": 1}}}`)
+	if err := applyOverrides(pluginDocs, codecDocs, common); err == nil {
+		t.Error("unknown override field accepted")
+	}
+}
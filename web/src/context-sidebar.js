@@ -65,6 +65,13 @@ export function createContextSidebar() {
 
     content.innerHTML = '';
 
+    if (info.breadcrumb?.length) {
+      const crumbs = document.createElement('div');
+      crumbs.className = 'sidebar-breadcrumb';
+      crumbs.textContent = info.breadcrumb.join(' › ');
+      content.appendChild(crumbs);
+    }
+
     switch (info.kind) {
       case 'top-level':
         renderTopLevel(content);
@@ -179,6 +186,20 @@ function renderPlugin(parent, info) {
   title.textContent = info.pluginName;
   parent.appendChild(title);
 
+  if (info.pluginVersion) {
+    const version = document.createElement('div');
+    version.className = 'sidebar-plugin-version';
+    version.textContent = `${info.pluginName} ${info.sectionType} v${info.pluginVersion}`;
+    parent.appendChild(version);
+  }
+
+  if (info.pluginDoc && info.pluginDoc.constraint) {
+    const constraint = document.createElement('div');
+    constraint.className = 'sidebar-plugin-version';
+    constraint.textContent = `Requires Logstash ${info.pluginDoc.constraint}`;
+    parent.appendChild(constraint);
+  }
+
   if (info.pluginDoc && info.pluginDoc.description) {
     const desc = document.createElement('div');
     desc.className = 'sidebar-description';
@@ -186,6 +207,36 @@ function renderPlugin(parent, info) {
     parent.appendChild(desc);
   }
 
+  if (info.pluginDoc && info.pluginDoc.longDescription) {
+    const more = document.createElement('details');
+    more.className = 'sidebar-more';
+    const summary = document.createElement('summary');
+    summary.textContent = 'more';
+    more.appendChild(summary);
+    const body = document.createElement('div');
+    body.className = 'sidebar-long-description';
+    body.textContent = info.pluginDoc.longDescription;
+    more.appendChild(body);
+    parent.appendChild(more);
+  }
+
+  if (info.hashOption) {
+    const hint = document.createElement('div');
+    hint.className = 'sidebar-hash-hint';
+    hint.textContent = `Inside the ${info.hashOption} hash of ${info.pluginName}: keys are free-form, not options.`;
+    if (info.optionDoc && info.optionDoc.description) {
+      hint.textContent += ' ' + info.optionDoc.description;
+    }
+    parent.appendChild(hint);
+  }
+
+  if (info.optionValueIssue) {
+    const issue = document.createElement('div');
+    issue.className = 'sidebar-value-issue';
+    issue.textContent = info.optionValueIssue;
+    parent.appendChild(issue);
+  }
+
   const subtitle = document.createElement('div');
   subtitle.className = 'sidebar-section-title';
   subtitle.style.fontSize = '12px';
@@ -201,12 +252,35 @@ function renderPlugin(parent, info) {
     return;
   }
 
+  // Options declared by shared mixins (e.g. SSL settings) are listed per
+  // mixin after the plugin's own.
+  const groups = new Map();
+  const own = [];
+  for (const opt of info.options) {
+    if (opt.source && opt.source.startsWith('mixin:')) {
+      if (!groups.has(opt.source)) groups.set(opt.source, []);
+      groups.get(opt.source).push(opt);
+    } else {
+      own.push(opt);
+    }
+  }
+  renderOptionList(parent, own, info.optionName);
+  for (const [source, options] of groups) {
+    const groupTitle = document.createElement('div');
+    groupTitle.className = 'sidebar-option-group';
+    groupTitle.textContent = `${source.split('/').pop()} options (shared mixin)`;
+    parent.appendChild(groupTitle);
+    renderOptionList(parent, options, info.optionName);
+  }
+}
+
+function renderOptionList(parent, options, highlighted) {
   const list = document.createElement('ul');
   list.className = 'sidebar-list';
-  for (const opt of info.options) {
+  for (const opt of options) {
     const li = document.createElement('li');
     li.className = 'sidebar-list-item';
-    if (info.optionName && opt.name === info.optionName) {
+    if (highlighted && opt.name === highlighted) {
       li.classList.add('highlighted');
     }
 
@@ -230,6 +304,16 @@ function renderPlugin(parent, info) {
       nameRow.appendChild(req);
     }
 
+    if (opt.sensitive) {
+      const secret = document.createElement('span');
+      secret.className = 'sidebar-item-sensitive';
+      secret.textContent = 'secret';
+      secret.title = opt.type === 'password'
+        ? 'Don\'t hardcode this value; use a ${VAR} keystore or environment reference.'
+        : 'Don\'t hardcode credentials in this URI; use a ${VAR} keystore or environment reference.';
+      nameRow.appendChild(secret);
+    }
+
     if (opt.default) {
       const def = document.createElement('span');
       def.className = 'sidebar-item-default';
@@ -259,7 +343,9 @@ function renderCodec(parent, info) {
 
   const desc = document.createElement('div');
   desc.className = 'sidebar-description';
-  desc.textContent = 'Available codecs for encoding/decoding data:';
+  desc.textContent = info.pluginName
+    ? `Codecs available to ${info.sectionType} ${info.pluginName}:`
+    : 'Available codecs for encoding/decoding data:';
   parent.appendChild(desc);
 
   if (!info.plugins || info.plugins.length === 0) {
@@ -25,33 +25,85 @@ output {
 `;
 
 async function logstashCompletionSource(context) {
-  const word = context.matchBefore(/[a-zA-Z_][a-zA-Z0-9_]*/);
+  // Words, a field reference being typed such as [http][st, or a grok
+  // pattern reference (%{)
+  const word = context.matchBefore(/(?:\[[\w@.-]*\])*\[[\w@.-]*|%\{\w*|[a-zA-Z_][a-zA-Z0-9_]*/);
   if (!word && !context.explicit) return null;
 
   const source = context.state.doc.toString();
   const result = await getCompletions(source, context.pos);
   if (!result.options || result.options.length === 0) return null;
 
+  const options = result.options.map(o => ({
+    ...o,
+    ...(o.insertText && { apply: applySnippet(o.insertText) }),
+    ...(o.deprecated && { boost: -1 }), // keep deprecated options last after filtering
+  }));
+
+  // Scored options were already fuzzy-matched and ordered by the parser:
+  // show them as given, and ask again on every keystroke.
+  if (options.some(o => o.score)) {
+    return { from: result.from, options, filter: false };
+  }
+
   return {
     from: result.from,
-    options: result.options,
-    validFor: /^[a-zA-Z_][a-zA-Z0-9_]*$/,
+    options,
+    validFor: /^(?:[a-zA-Z_][a-zA-Z0-9_]*|(?:\[[\w@.-]*\]?)+)$/,
+  };
+}
+
+// Returns a completion apply function inserting multi-line text with each
+// following line indented like the line it's inserted on. A "..."
+// placeholder is selected; otherwise the cursor goes after the first " => ".
+function applySnippet(text) {
+  return (view, completion, from, to) => {
+    const line = view.state.doc.lineAt(from);
+    const indent = /^\s*/.exec(line.text)[0];
+    const insert = text.replace(/\n/g, '\n' + indent);
+    const placeholder = insert.indexOf('...');
+    const selection = placeholder >= 0
+      ? { anchor: from + placeholder, head: from + placeholder + 3 }
+      : { anchor: from + insert.indexOf(' => ') + 4 };
+    view.dispatch({ changes: { from, to, insert }, selection });
   };
 }
 
-function createLogstashLinter() {
+// Renders required/deprecated badges next to option completions.
+function renderCompletionBadges(completion) {
+  if (!completion.required && !completion.deprecated) return null;
+  const wrap = document.createElement('span');
+  for (const kind of ['required', 'deprecated']) {
+    if (!completion[kind]) continue;
+    const badge = document.createElement('span');
+    badge.className = `cm-completionBadge cm-completionBadge-${kind}`;
+    badge.textContent = kind;
+    wrap.appendChild(badge);
+  }
+  return wrap;
+}
+
+// lintOptions are passed to parseLogstash, e.g. { allErrors: true } to
+// report every broken section instead of only the first syntax error.
+function createLogstashLinter(lintOptions = {}) {
   return linter(async (view) => {
     const doc = view.state.doc.toString();
     if (!doc.trim()) return [];
 
     try {
-      const result = await parseLogstash(doc);
+      const result = await parseLogstash(doc, lintOptions);
 
       const diagnostics = (result.diagnostics || []).map(d => ({
         from: Math.max(0, d.from),
         to: Math.min(d.to, doc.length),
         severity: d.severity,
         message: d.message,
+        actions: d.fix ? [{
+          name: d.fix.label,
+          apply(view) {
+            view.dispatch({ changes: { from: d.fix.from, to: d.fix.to, insert: d.fix.insert } });
+          },
+        }] : undefined,
       }));
 
       if (!result.ok && result.farthest && !diagnostics.some(d => d.from === result.farthest.from)) {
@@ -59,7 +111,9 @@ function createLogstashLinter() {
           from: Math.max(0, result.farthest.from),
           to: Math.min(result.farthest.to, doc.length),
           severity: result.farthest.severity,
-          message: result.farthest.message,
+          message: result.farthest.reasons.length > 1
+            ? result.farthest.reasons.map(r => `• ${r}`).join('\n')
+            : result.farthest.message,
         });
       }
 
@@ -71,7 +125,7 @@ function createLogstashLinter() {
   }, { delay: 300 });
 }
 
-export function createEditor(parent) {
+export function createEditor(parent, lintOptions = {}) {
   const linterCompartment = new Compartment();
   let cursorCallback = null;
 
@@ -80,9 +134,12 @@ export function createEditor(parent) {
       doc: SAMPLE,
       extensions: [
         basicSetup,
-        autocompletion({ override: [logstashCompletionSource] }),
+        autocompletion({
+          override: [logstashCompletionSource],
+          addToOptions: [{ render: renderCompletionBadges, position: 90 }],
+        }),
         lintGutter(),
-        linterCompartment.of(createLogstashLinter()),
+        linterCompartment.of(createLogstashLinter(lintOptions)),
         EditorView.theme({
           // Layout
           '&': { height: '100%', backgroundColor: '#1e1e1e', color: '#d4d4d4' },
@@ -103,11 +160,15 @@ export function createEditor(parent) {
           '.cm-tooltip-section': { borderTop: '1px solid #3c3c3c' },
           '.cm-completionDetail': { color: '#888' },
           '.cm-completionMatchedText': { color: '#4ec9b0', textDecoration: 'none' },
+          '.cm-completionBadge': { marginLeft: '6px', padding: '0 4px', borderRadius: '3px', fontSize: '0.8em' },
+          '.cm-completionBadge-required': { backgroundColor: '#4d3800', color: '#cca700' },
+          '.cm-completionBadge-deprecated': { backgroundColor: '#5a1d1d', color: '#f48771', textDecoration: 'line-through' },
           // Lint diagnostics
           '.cm-tooltip-lint': { backgroundColor: '#252526', border: '1px solid #3c3c3c' },
           '.cm-diagnostic': { color: '#d4d4d4' },
           '.cm-diagnostic-error': { color: '#f44747' },
           '.cm-diagnostic-warning': { color: '#cca700' },
+          '.cm-diagnostic-info': { color: '#75beff' },
           '.cm-diagnosticAction': { backgroundColor: '#3c3c3c', color: '#d4d4d4' },
           '.cm-diagnosticSource': { color: '#888' },
           // Panels (lint panel, search)
@@ -139,9 +200,13 @@ export function createEditor(parent) {
     },
     relint() {
       view.dispatch({
-        effects: linterCompartment.reconfigure(createLogstashLinter()),
+        effects: linterCompartment.reconfigure(createLogstashLinter(lintOptions)),
       });
     },
+    setLintOptions(options) {
+      lintOptions = options;
+      this.relint();
+    },
     onCursorActivity(callback) {
       cursorCallback = callback;
     },
@@ -4,6 +4,8 @@ import { createPipelinePanel } from './pipeline-panel.js';
 import { createImportDataPage } from './import-data.js';
 import { createContextSidebar } from './context-sidebar.js';
 
+const ALL_ERRORS_KEY = 'editor-all-syntax-errors';
+
 function navigate(hash) {
   const page = hash.replace('#', '') || 'editor';
   document.querySelectorAll('.page').forEach(el => {
@@ -17,7 +19,15 @@ function navigate(hash) {
 async function init() {
   const parserStatus = { text: 'Loading WASM parser...', state: '' };
 
-  const editorApi = createEditor(document.getElementById('editor'));
+  // Reporting every broken section is opt-in; by default the linter shows
+  // the parser's first syntax error.
+  const allErrors = document.getElementById('all-errors-toggle');
+  allErrors.checked = localStorage.getItem(ALL_ERRORS_KEY) === 'true';
+  const editorApi = createEditor(document.getElementById('editor'), { allErrors: allErrors.checked });
+  allErrors.addEventListener('change', () => {
+    localStorage.setItem(ALL_ERRORS_KEY, allErrors.checked);
+    editorApi.setLintOptions({ allErrors: allErrors.checked });
+  });
 
   const pageEditor = document.getElementById('page-editor');
   const panel = createPipelinePanel(editorApi, parserStatus);
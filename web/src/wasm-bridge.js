@@ -13,9 +13,115 @@ export async function initWasm() {
   readyResolve();
 }
 
-export async function parseLogstash(source) {
+// options: { timings: true } adds a parse/validate timing breakdown (ms);
+// { profile: 'beginner' | 'ci' | 'strict' } selects a lint profile (beginner
+// also turns on the style notes, strict every opt-in lint below);
+// { allErrors: true } reports syntax errors in every broken section.
+// { lintIds: true } notes plugins without an id.
+// { strict: true } reports unknown plugins, codecs and options as errors
+// rather than warnings, whatever the profile.
+// { style: true } adds info notes, with fixes, for valid but untidy syntax:
+// => without surrounding spaces, name{ and trailing whitespace.
+// { pipelines: true } validates each pipeline separated by a
+// "# --- pipeline ---" line on its own (pass a string for another delimiter);
+// the result then has multiPipeline: true.
+// Diagnostics carry 1-based fromLine/fromCol/toLine/toCol besides the
+// from/to offsets. Failed parses carry farthest: the deepest position
+// reached, as a warning diagnostic plus reasons, the parser's messages there.
+export async function parseLogstash(source, options = {}) {
   if (!wasmReady) await readyPromise;
-  const jsonStr = window.parseLogstashConfig(source);
+  const jsonStr = window.parseLogstashConfig(source, options);
+  return JSON.parse(jsonStr);
+}
+
+// Validates a config delivered as an (async) iterable of string chunks,
+// for files too large to pass as a single string.
+export async function validateStream(chunks) {
+  if (!wasmReady) await readyPromise;
+  const { id } = JSON.parse(window.validateLogstashStreamBegin());
+  try {
+    for await (const chunk of chunks) {
+      const result = JSON.parse(window.validateLogstashStreamChunk(id, chunk));
+      if (!result.ok) throw new Error(result.error);
+    }
+  } catch (err) {
+    window.validateLogstashStreamEnd(id); // release the buffer
+    throw err;
+  }
+  return JSON.parse(window.validateLogstashStreamEnd(id));
+}
+
+// Returns { ok, formatted, error }; formatted is the input unchanged when
+// it doesn't parse. options: { quotes: 'double' | 'single' | 'preserve' }
+// normalizes quoted strings where that doesn't change their escaping;
+// { style: 'compact' } puts short plugins on one line ('expanded' default);
+// { indent: 4 } or { indent: 'tab' } sets the indentation (1-8 spaces,
+// default 2); other values give ok: false.
+export async function formatConfig(source, options = {}) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.formatLogstashConfig(source, options);
+  return JSON.parse(jsonStr);
+}
+
+// Validates independently of the editor's parse call. Returns
+// { ok, diagnostics, plugins, options }; sections that parse are validated
+// even when others don't. Diagnostics have lines and columns as above.
+export async function validateConfig(source) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.validateLogstashConfig(source);
+  return JSON.parse(jsonStr);
+}
+
+// Returns [{ from, to }, ...] for every { } block: from is just past the {,
+// to is at the }.
+export async function getFoldRanges(source) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashFoldRanges(source);
+  return JSON.parse(jsonStr);
+}
+
+// Returns [{ from, to, type }, ...] sorted by from, where type is 'section',
+// 'plugin', 'plugin-unknown', 'option', 'option-unknown', 'codec', 'value' or
+// 'field-ref'. Sources that don't parse are classified by a lexical scan.
+export async function getSemanticTokens(source) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashSemanticTokens(source);
+  return JSON.parse(jsonStr);
+}
+
+export async function getDiagnosticsSummary(source) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashDiagnosticsSummary(source);
+  return JSON.parse(jsonStr);
+}
+
+// Extended help for the diagnostic at offset. Returns { title, code, message,
+// suggestions: [{ name, description }, ...] }: the valid options, plugins or
+// codecs for unknown ones; title is empty when there is no diagnostic there.
+export async function explainDiagnostic(source, offset) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.explainLogstashDiagnostic(source, offset);
+  return JSON.parse(jsonStr);
+}
+
+// Fuzzy search over the active registry's plugin, codec and option names,
+// for a command palette. Returns up to 50 matches, best first:
+// [{ kind: 'plugin' | 'codec' | 'option', name, sectionType, pluginName,
+// description, score }, ...]; common options have no pluginName.
+export async function searchRegistry(query) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.searchLogstashRegistry(query);
+  return JSON.parse(jsonStr);
+}
+
+// Debugging aid: the parsed AST as { ok, ast }, where ast is a tree of
+// { kind, name, value, offset, line, column, children } nodes (config,
+// section, plugin, branch/if/else, attribute, value and codec kinds).
+// Sources that don't parse give { ok: false, error } with the syntax error
+// as a diagnostic.
+export async function dumpAST(source) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.dumpLogstashAST(source);
   return JSON.parse(jsonStr);
 }
 
@@ -25,6 +131,24 @@ export async function getVersions() {
   return JSON.parse(jsonStr);
 }
 
+export async function listVersions() {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.listLogstashVersions();
+  return JSON.parse(jsonStr);
+}
+
+// Compares two registry versions without changing the active one. Returns
+// { from, to, plugins: { input: { added, removed }, ... }, codecs, options }.
+export async function diffVersions(from, to) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.diffLogstashVersions(from, to);
+  const result = JSON.parse(jsonStr);
+  if (!result.ok) {
+    throw new Error(result.error);
+  }
+  return result;
+}
+
 export async function getCompletions(source, pos) {
   if (!wasmReady) await readyPromise;
   const jsonStr = window.getLogstashCompletions(source, pos);
@@ -37,6 +161,52 @@ export async function getContextInfo(source, pos) {
   return JSON.parse(jsonStr);
 }
 
+// Documentation for one plugin, independent of the cursor. Returns
+// { sectionType, pluginName, description, longDescription, options }; unknown
+// plugins give an empty description and options list.
+export async function getPluginDoc(sectionType, pluginName) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashPluginDoc(sectionType, pluginName);
+  return JSON.parse(jsonStr);
+}
+
+export async function getAvailableCodecs(sectionType, pluginName) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashAvailableCodecs(sectionType, pluginName);
+  return JSON.parse(jsonStr).codecs;
+}
+
+export async function getDocUrl(sectionType, pluginName, optionName = '') {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashDocUrl(sectionType, pluginName, optionName);
+  return JSON.parse(jsonStr).url;
+}
+
+export async function getInsertPosition(source, sectionType) {
+  if (!wasmReady) await readyPromise;
+  const jsonStr = window.getLogstashInsertPosition(source, sectionType);
+  const result = JSON.parse(jsonStr);
+  if (!result.ok) {
+    throw new Error(result.error);
+  }
+  return result;
+}
+
+// Registers registry data (object or JSON string) under a version, making it
+// selectable with setVersion without rebuilding the WASM. options:
+// { activate: true } also switches to it. Malformed data throws and leaves
+// the active registry untouched.
+export async function loadRegistry(version, registry, options = {}) {
+  if (!wasmReady) await readyPromise;
+  const json = typeof registry === 'string' ? registry : JSON.stringify(registry);
+  const jsonStr = window.loadLogstashRegistry(version, json, Boolean(options.activate));
+  const result = JSON.parse(jsonStr);
+  if (!result.ok) {
+    throw new Error(result.error);
+  }
+  return result;
+}
+
 export async function setVersion(version) {
   if (!wasmReady) await readyPromise;
   const jsonStr = window.setLogstashVersion(version);